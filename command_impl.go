@@ -0,0 +1,85 @@
+package pod
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// DefaultCommandRunner is used by the functions that do not take a runner.
+var DefaultCommandRunner CommandRunner = new(ExecCommandRunner)
+
+// ** ExecCommandRunner Impl **
+func (s *ExecCommandRunner) RunPod(ctx context.Context, args ...string) ([]byte, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); !ok && s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	pod := s.Pod
+	if len(pod) == 0 {
+		pod = []string{"pod"}
+	}
+	cmdArgs := append(append([]string{}, pod[1:]...), args...)
+	cmd := exec.CommandContext(ctx, pod[0], cmdArgs...)
+	cmd.Dir = s.Dir
+	if len(s.Env) > 0 {
+		cmd.Env = append(os.Environ(), s.Env...)
+	}
+	b, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, errors.New(strings.Join(pod, " ") + " " + strings.Join(args, " ") + ": " + strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// ** FakeCommandRunner Impl **
+func (s *FakeCommandRunner) RunPod(ctx context.Context, args ...string) ([]byte, error) {
+	s.mutex.Lock()
+	s.Calls = append(s.Calls, args)
+	s.mutex.Unlock()
+	if ctx != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if b, ok := s.Outputs[strings.Join(args, " ")]; ok {
+		return b, nil
+	}
+	if s.FixtureDir != "" && len(args) == 3 && args[0] == "ipc" {
+		baseName := path.Base(args[2])
+		switch args[1] {
+		case "spec":
+			baseName = strings.TrimSuffix(baseName, ".podspec") + ".podspec.json"
+		case "podfile":
+			baseName += ".yaml"
+		default:
+			baseName = ""
+		}
+		if baseName != "" {
+			if b, err := ioutil.ReadFile(path.Join(s.FixtureDir, baseName)); err == nil {
+				return b, nil
+			}
+		}
+	}
+	return nil, errors.New("FakeCommandRunner: 没有找到 pod " + strings.Join(args, " ") + " 的输出")
+}
+
+// ** Func Private **
+func commandRunnerOrDefault(runner CommandRunner) CommandRunner {
+	if runner == nil {
+		return DefaultCommandRunner
+	}
+	return runner
+}
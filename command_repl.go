@@ -0,0 +1,212 @@
+package pod
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// `pod ipc repl` ends the output of every command with this signal
+var replEndOfOutput = []byte("\n\r\n")
+
+type replWorker struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+type replResult struct {
+	b   []byte
+	err error
+}
+
+// ** ReplCommandRunner Impl **
+func NewReplCommandRunner(base *ExecCommandRunner, size int) *ReplCommandRunner {
+	if size < 1 {
+		size = 1
+	}
+	aRunner := &ReplCommandRunner{Size: size}
+	if base != nil {
+		aRunner.ExecCommandRunner = *base
+	}
+	return aRunner
+}
+
+func (s *ReplCommandRunner) RunPod(ctx context.Context, args ...string) ([]byte, error) {
+	if len(args) != 3 || args[0] != "ipc" || args[1] != "spec" || strings.ContainsAny(args[2], " \t\n") {
+		return s.ExecCommandRunner.RunPod(ctx, args...)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); !ok && s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	s.initPool()
+
+	var w *replWorker
+	select {
+	case w = <-s.pool:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if s.isClosed() {
+		s.pool <- nil
+		return s.ExecCommandRunner.RunPod(ctx, args...)
+	}
+	if w == nil {
+		var err error
+		if w, err = s.startWorker(ctx); err != nil {
+			s.pool <- nil
+			return nil, err
+		}
+	}
+
+	c := make(chan *replResult, 1)
+	go func() {
+		b, err := w.exec("spec " + args[2])
+		c <- &replResult{b, err}
+	}()
+	select {
+	case res := <-c:
+		if res.err == nil {
+			s.pool <- w
+			return res.b, nil
+		}
+		// The spec may have crashed the worker, run it alone to get the real error
+		w.stop()
+		s.pool <- nil
+		return s.ExecCommandRunner.RunPod(ctx, args...)
+	case <-ctx.Done():
+		w.stop()
+		<-c
+		s.pool <- nil
+		return nil, ctx.Err()
+	}
+}
+
+// Close stops all the repl processes. Later calls run `pod ipc spec` once per
+// spec.
+func (s *ReplCommandRunner) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mutex.Unlock()
+	s.initPool()
+	workers := make([]*replWorker, 0, s.Size)
+	for i := 0; i < s.Size; i++ {
+		workers = append(workers, <-s.pool)
+	}
+	for _, w := range workers {
+		if w != nil {
+			w.close()
+		}
+		s.pool <- nil
+	}
+	return nil
+}
+
+func (s *ReplCommandRunner) initPool() {
+	s.once.Do(func() {
+		s.pool = make(chan *replWorker, s.Size)
+		for i := 0; i < s.Size; i++ {
+			s.pool <- nil
+		}
+	})
+}
+
+func (s *ReplCommandRunner) isClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.closed
+}
+
+// startWorker starts a repl and waits for its version banner until ctx is
+// done. The repl is not bound to ctx, it outlives the request.
+func (s *ReplCommandRunner) startWorker(ctx context.Context) (*replWorker, error) {
+	pod := s.Pod
+	if len(pod) == 0 {
+		pod = []string{"pod"}
+	}
+	cmdArgs := append(append([]string{}, pod[1:]...), "ipc", "repl")
+	cmd := exec.Command(pod[0], cmdArgs...)
+	cmd.Dir = s.Dir
+	if len(s.Env) > 0 {
+		cmd.Env = append(os.Environ(), s.Env...)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	w := &replWorker{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}
+	// Skip the version banner
+	c := make(chan error, 1)
+	go func() {
+		_, err := w.read()
+		c <- err
+	}()
+	select {
+	case err := <-c:
+		if err != nil {
+			w.stop()
+			return nil, errors.New("启动 pod ipc repl 失败: " + err.Error())
+		}
+		return w, nil
+	case <-ctx.Done():
+		w.stop()
+		<-c
+		return nil, ctx.Err()
+	}
+}
+
+// ** replWorker Impl **
+func (s *replWorker) exec(command string) ([]byte, error) {
+	if _, err := io.WriteString(s.stdin, command+"\n"); err != nil {
+		return nil, err
+	}
+	return s.read()
+}
+
+func (s *replWorker) read() ([]byte, error) {
+	var buffer bytes.Buffer
+	for {
+		line, err := s.stdout.ReadBytes('\n')
+		buffer.Write(line)
+		if bytes.HasSuffix(buffer.Bytes(), replEndOfOutput) {
+			return bytes.TrimSpace(buffer.Bytes()[:buffer.Len()-len(replEndOfOutput)]), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *replWorker) close() {
+	s.stdin.Close()
+	s.cmd.Wait()
+}
+
+func (s *replWorker) stop() {
+	s.stdin.Close()
+	if s.cmd.Process != nil {
+		s.cmd.Process.Kill()
+	}
+	s.cmd.Wait()
+}
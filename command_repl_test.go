@@ -0,0 +1,165 @@
+package pod
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testFakePod is a `pod` answering `ipc repl` and `ipc spec` with the name of
+// the spec and how it was run. A spec named crash kills the repl. Every
+// repl start appends a line to $POD_LOG, the repl hangs before its banner
+// when $POD_HANG is set.
+const testFakePod = `#!/bin/sh
+name() { basename "$1" .podspec; }
+if [ "$1 $2" = "ipc repl" ]; then
+  echo start >> "$POD_LOG"
+  if [ -n "$POD_HANG" ]; then
+    exec sleep 30
+  fi
+  printf 'version 1.12.1\n\r\n'
+  while read command file; do
+    case "$file" in
+      *crash*) exit 1 ;;
+    esac
+    printf '{"name": "%s", "via": "repl"}\n\r\n' "$(name "$file")"
+  done
+  exit 0
+fi
+if [ "$1 $2" = "ipc spec" ]; then
+  printf '{"name": "%s", "via": "exec"}\n' "$(name "$3")"
+  exit 0
+fi
+echo "$@"
+`
+
+// newTestReplRunner puts testFakePod on PATH and returns a runner of size
+// workers with the file counting the repl starts.
+func newTestReplRunner(t *testing.T, size int) (*ReplCommandRunner, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(path.Join(dir, "pod"), []byte(testFakePod), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	logFile := path.Join(dir, "starts.log")
+	aRunner := NewReplCommandRunner(&ExecCommandRunner{Env: []string{"POD_LOG=" + logFile}}, size)
+	t.Cleanup(func() { aRunner.Close() })
+	return aRunner, logFile
+}
+
+func testReplStarts(logFile string) int {
+	b, _ := ioutil.ReadFile(logFile)
+	return strings.Count(string(b), "start")
+}
+
+func TestReplCommandRunner(t *testing.T) {
+	tests := []struct {
+		name       string
+		calls      [][]string
+		close      int // calls made after Close, 0 for none
+		want       []string
+		wantStarts int
+	}{
+		{
+			name:       "specs share a worker",
+			calls:      [][]string{{"ipc", "spec", "Foo.podspec"}, {"ipc", "spec", "Bar.podspec"}},
+			want:       []string{`{"name": "Foo", "via": "repl"}`, `{"name": "Bar", "via": "repl"}`},
+			wantStarts: 1,
+		},
+		{
+			name:       "crashed worker is replaced",
+			calls:      [][]string{{"ipc", "spec", "Foo.podspec"}, {"ipc", "spec", "crash.podspec"}, {"ipc", "spec", "Bar.podspec"}},
+			want:       []string{`{"name": "Foo", "via": "repl"}`, `{"name": "crash", "via": "exec"}`, `{"name": "Bar", "via": "repl"}`},
+			wantStarts: 2,
+		},
+		{
+			name:  "other commands run alone",
+			calls: [][]string{{"ipc", "podfile", "Podfile"}, {"ipc", "spec", "My Foo.podspec"}},
+			want:  []string{"ipc podfile Podfile", `{"name": "My Foo", "via": "exec"}`},
+		},
+		{
+			name:       "closed",
+			calls:      [][]string{{"ipc", "spec", "Foo.podspec"}, {"ipc", "spec", "Bar.podspec"}},
+			close:      1,
+			want:       []string{`{"name": "Foo", "via": "repl"}`, `{"name": "Bar", "via": "exec"}`},
+			wantStarts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aRunner, logFile := newTestReplRunner(t, 1)
+			for idx, args := range tt.calls {
+				if tt.close > 0 && idx == len(tt.calls)-tt.close {
+					if err := aRunner.Close(); err != nil {
+						t.Fatal(err)
+					}
+				}
+				b, err := aRunner.RunPod(context.Background(), args...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := strings.TrimSpace(string(b)); got != tt.want[idx] {
+					t.Errorf("RunPod(%v) = %s, want %s", args, got, tt.want[idx])
+				}
+			}
+			if starts := testReplStarts(logFile); starts != tt.wantStarts {
+				t.Errorf("repl started %d times, want %d", starts, tt.wantStarts)
+			}
+		})
+	}
+}
+
+func TestReplCommandRunnerSize(t *testing.T) {
+	aRunner, logFile := newTestReplRunner(t, 2)
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for idx := range errs {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			_, errs[idx] = aRunner.RunPod(context.Background(), "ipc", "spec", "Foo.podspec")
+		}(idx)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if starts := testReplStarts(logFile); starts < 1 || starts > 2 {
+		t.Errorf("repl started %d times, want at most 2", starts)
+	}
+}
+
+func TestReplCommandRunnerHangingBanner(t *testing.T) {
+	aRunner, logFile := newTestReplRunner(t, 1)
+	t.Setenv("POD_HANG", "1")
+	aRunner.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	_, err := aRunner.RunPod(context.Background(), "ipc", "spec", "Foo.podspec")
+	if err != context.DeadlineExceeded {
+		t.Errorf("RunPod() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("RunPod() took %v, want it to stop at the timeout", elapsed)
+	}
+	if starts := testReplStarts(logFile); starts != 1 {
+		t.Errorf("repl started %d times, want 1", starts)
+	}
+
+	// The next request starts a new repl
+	t.Setenv("POD_HANG", "")
+	b, err := aRunner.RunPod(context.Background(), "ipc", "spec", "Foo.podspec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.TrimSpace(string(b)), `{"name": "Foo", "via": "repl"}`; got != want {
+		t.Errorf("RunPod() = %s, want %s", got, want)
+	}
+}
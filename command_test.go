@@ -0,0 +1,192 @@
+package pod
+
+import (
+	"context"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExecCommandRunner(t *testing.T) {
+	dir := t.TempDir()
+	shell := func(script string) []string { return []string{"sh", "-c", script, "pod"} }
+	tests := []struct {
+		name    string
+		runner  *ExecCommandRunner
+		want    string
+		wantErr string
+	}{
+		{name: "args", runner: &ExecCommandRunner{Pod: shell(`echo "$@"`)}, want: "ipc spec Foo.podspec\n"},
+		{name: "env", runner: &ExecCommandRunner{Pod: shell(`echo "$POD_TEST"`), Env: []string{"POD_TEST=1"}}, want: "1\n"},
+		{name: "dir", runner: &ExecCommandRunner{Pod: shell(`basename "$(pwd)"`), Dir: dir}, want: path.Base(dir) + "\n"},
+		{name: "stderr", runner: &ExecCommandRunner{Pod: shell(`echo broken >&2; exit 1`)}, wantErr: "ipc spec Foo.podspec: broken"},
+		{name: "timeout", runner: &ExecCommandRunner{Pod: shell(`exec sleep 5`), Timeout: 50 * time.Millisecond}, wantErr: context.DeadlineExceeded.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.runner.RunPod(context.Background(), "ipc", "spec", "Foo.podspec")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("RunPod() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("RunPod() = %q, want %q", b, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeCommandRunner(t *testing.T) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(path.Join(dir, "Foo.podspec.json"), []byte(`{"name": "Foo"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "Podfile.yaml"), []byte("target_definitions: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runner := &FakeCommandRunner{
+		Outputs:    map[string][]byte{"ipc spec Bar.podspec": []byte(`{"name": "Bar"}`)},
+		FixtureDir: dir,
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "output", args: []string{"ipc", "spec", "Bar.podspec"}, want: `{"name": "Bar"}`},
+		{name: "spec fixture", args: []string{"ipc", "spec", "/specs/Foo/Foo.podspec"}, want: `{"name": "Foo"}`},
+		{name: "podfile fixture", args: []string{"ipc", "podfile", "/app/Podfile"}, want: "target_definitions: []\n"},
+		{name: "no fixture", args: []string{"ipc", "spec", "Baz.podspec"}, wantErr: true},
+		{name: "other command", args: []string{"repo", "update"}, wantErr: true},
+		{name: "canceled", ctx: canceled, args: []string{"ipc", "spec", "Bar.podspec"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		ctx := tt.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		b, err := runner.RunPod(ctx, tt.args...)
+		if (err != nil) != tt.wantErr || string(b) != tt.want {
+			t.Errorf("%s: RunPod() = %q, %v, want %q", tt.name, b, err, tt.want)
+		}
+	}
+	if len(runner.Calls) != len(tests) || !reflect.DeepEqual(runner.Calls[0], tests[0].args) {
+		t.Errorf("Calls = %v, want every call", runner.Calls)
+	}
+}
+
+func TestNewPodfileWithRunner(t *testing.T) {
+	dir := t.TempDir()
+	fixtures := map[string]string{
+		"Native":    "target 'App' do\n  pod 'A', '~> 1.0'\nend\n",
+		"Ruby":      "def shared\n  pod 'A'\nend\ntarget 'App' do\n  shared\nend\n",
+		"Ruby.yaml": "target_definitions:\n- name: Pods\n  abstract: true\n  children:\n  - name: App\n    dependencies:\n    - A:\n      - \"~> 2.0\"\n",
+		"NoFixture": "def shared\nend\n",
+	}
+	for name, content := range fixtures {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name      string
+		file      string
+		want      string
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "parsed natively", file: "Native", want: "~> 1.0"},
+		{name: "falls back to ipc", file: "Ruby", want: "~> 2.0", wantCalls: 1},
+		{name: "ipc error", file: "NoFixture", wantCalls: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &FakeCommandRunner{FixtureDir: dir}
+			aPodfile, err := NewPodfileWithRunner(context.Background(), runner, path.Join(dir, tt.file))
+			if len(runner.Calls) != tt.wantCalls {
+				t.Errorf("Calls = %v, want %d", runner.Calls, tt.wantCalls)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Error("want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			aTarget := aPodfile.TargetWithName("App")
+			if aTarget == nil || aTarget.ModuleWithName("A") == nil {
+				t.Fatalf("no module A in App")
+			}
+			if v := aTarget.ModuleWithName("A").V; v != tt.want {
+				t.Errorf("A = %q, want %q", v, tt.want)
+			}
+			if aPodfile.Runner != runner {
+				t.Error("Runner is not kept")
+			}
+		})
+	}
+}
+
+func TestReadSpecWithRunner(t *testing.T) {
+	dir := t.TempDir()
+	fixtures := map[string]string{
+		"Native.podspec":    "Pod::Spec.new do |s|\n  s.name = 'Native'\n  s.version = '1.0'\nend\n",
+		"Ruby.podspec":      "Pod::Spec.new do |s|\n  s.name = 'Ruby'\n  s.version = File.read('VERSION')\nend\n",
+		"Ruby.podspec.json": `{"name": "Ruby", "version": "2.0"}`,
+		"NoFixture.podspec": "Pod::Spec.new do |s|\n  s.version = File.read('VERSION')\nend\n",
+		"Json.podspec.json": `{"name": "Json", "version": "3.0"}`,
+	}
+	for name, content := range fixtures {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name      string
+		file      string
+		want      string
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "podspec evaluated natively", file: "Native.podspec", want: "1.0"},
+		{name: "podspec through ipc", file: "Ruby.podspec", want: "2.0", wantCalls: 1},
+		{name: "ipc error", file: "NoFixture.podspec", wantCalls: 1, wantErr: true},
+		{name: "json spec", file: "Json.podspec.json", want: "3.0"},
+		{name: "missing file", file: "Missing.podspec", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &FakeCommandRunner{FixtureDir: dir}
+			aSpec, err := ReadSpecWithRunner(context.Background(), runner, path.Join(dir, tt.file))
+			if len(runner.Calls) != tt.wantCalls {
+				t.Errorf("Calls = %v, want %d", runner.Calls, tt.wantCalls)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Error("want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if aSpec.Version != tt.want || aSpec.FilePath != path.Join(dir, tt.file) {
+				t.Errorf("spec = %s %s, want %s %s", aSpec.Version, aSpec.FilePath, tt.want, tt.file)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package pod
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CommandRunner runs a CocoaPods command, args do not include the `pod`
+// executable itself, e.g. ("ipc", "spec", "Foo.podspec").
+type CommandRunner interface {
+	RunPod(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// ExecCommandRunner runs CocoaPods as an external process. Pod defaults to
+// []string{"pod"}, use []string{"bundle", "exec", "pod"} for a bundler setup.
+// Timeout is applied to every call whose context has no deadline.
+type ExecCommandRunner struct {
+	Pod     []string
+	Dir     string
+	Env     []string
+	Timeout time.Duration
+}
+
+// FakeCommandRunner answers CocoaPods commands from fixtures, for tests that
+// must run without Ruby. Outputs is keyed by the space joined args. Commands
+// not in Outputs are looked up in FixtureDir: `ipc spec Foo.podspec` reads
+// <FixtureDir>/Foo.podspec.json and `ipc podfile Podfile` reads
+// <FixtureDir>/Podfile.yaml.
+type FakeCommandRunner struct {
+	Outputs    map[string][]byte
+	FixtureDir string
+	Calls      [][]string
+
+	mutex sync.Mutex
+}
+
+// ReplCommandRunner keeps up to Size long lived `pod ipc repl` processes and
+// sends every `ipc spec` command to one of them, other commands are run by
+// the embedded ExecCommandRunner. A worker that crashes is replaced on the
+// next call. Call Close to stop the processes.
+type ReplCommandRunner struct {
+	ExecCommandRunner
+	Size int
+
+	pool   chan *replWorker
+	once   sync.Once
+	mutex  sync.Mutex
+	closed bool
+}
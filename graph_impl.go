@@ -0,0 +1,299 @@
+package pod
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+
+	fdt "github.com/go-hayden-base/foundation"
+)
+
+// ** Func Public **
+
+// NewDependGraphWithMapPodfile builds the graph of a resolved MapPodfile, the
+// modules written in the Podfile are the roots.
+func NewDependGraphWithMapPodfile(aMapPodfile *MapPodfile, opts *DependGraphOptions) *DependGraph {
+	aGraph := newDependGraph("Pods")
+	if aMapPodfile == nil {
+		return aGraph
+	}
+	for _, aModule := range aMapPodfile.Map {
+		aNode := aGraph.node(aModule.Name)
+		aNode.Version = aModule.UsefulV
+		aNode.State = aModule.State()
+		depends, _ := aModule.Depends()
+		for _, aDepend := range depends {
+			aGraph.node(aDepend.N)
+			aNode.Depends = append(aNode.Depends, &DependBase{N: aDepend.N, V: aDepend.V})
+		}
+		if !aModule.IsImplicit() {
+			aGraph.Roots = append(aGraph.Roots, aModule.Name)
+		}
+	}
+	return aGraph.finish(opts)
+}
+
+// NewDependGraphWithSpec builds the dependency closure of name inside aSpec
+// on opts.Platform. A subspec depends on its default subspecs and inherits
+// the dependencies of its parents, dependencies on other pods are leaves.
+func NewDependGraphWithSpec(aSpec *Spec, name string, opts *DependGraphOptions) *DependGraph {
+	if name == "" && aSpec != nil {
+		name = aSpec.Name
+	}
+	aGraph := newDependGraph(name)
+	if aSpec == nil || aSpec.SubspecWithPath(name) == nil {
+		return aGraph
+	}
+	platform := SpecPlatformAny
+	if opts != nil {
+		platform = opts.Platform
+	}
+	aGraph.Roots = append(aGraph.Roots, name)
+	queue := []string{name}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		aNode := aGraph.node(p)
+		aNode.Version = aSpec.Version
+		specs := aSpec.getPathSubspecs(p)
+		depends := make(map[string]string)
+		for _, spec := range specs {
+			mergeDpendMap(depends, spec.GetExcludeSubspecDepends(platform))
+		}
+		last := specs[len(specs)-1]
+		for _, sub := range last.Subspecs {
+			if last.IsDefaultSpec(sub.Name) {
+				depends[p+"/"+sub.Name] = ""
+			}
+		}
+		for _, n := range sortedDependNames(depends) {
+			aNode.Depends = append(aNode.Depends, &DependBase{N: n, V: depends[n]})
+			if _, ok := aGraph.Nodes[n]; ok {
+				continue
+			}
+			aGraph.node(n)
+			if fdt.StrSplitFirst(n, "/") == aSpec.Name && aSpec.SubspecWithPath(n) != nil {
+				queue = append(queue, n)
+			}
+		}
+	}
+	return aGraph.finish(opts)
+}
+
+// ** DependGraph Impl **
+
+// DOT renders the graph for Graphviz. Local modules are filled boxes,
+// implicit modules are dashed and new modules are green.
+func (s *DependGraph) DOT() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("digraph " + strconv.Quote(s.Name) + " {\n")
+	buffer.WriteString("  rankdir=LR;\n  node [shape=ellipse];\n")
+	for _, name := range s.sortedNames() {
+		aNode := s.Nodes[name]
+		attrs := []string{"label=" + strconv.Quote(aNode.label())}
+		if aNode.State&StateMapPodfileModuleLocal != 0 {
+			attrs = append(attrs, "shape=box", "style=filled", "fillcolor=lightblue")
+		}
+		if aNode.State&StateMapPodfileModuleImplicit != 0 {
+			attrs = append(attrs, "style=dashed")
+		}
+		if aNode.State&StateMapPodfileModuleNew != 0 {
+			attrs = append(attrs, "color=darkgreen", "fontcolor=darkgreen")
+		}
+		buffer.WriteString("  " + strconv.Quote(name) + " [" + strings.Join(attrs, ", ") + "];\n")
+	}
+	for _, name := range s.sortedNames() {
+		for _, aDepend := range s.Nodes[name].Depends {
+			buffer.WriteString("  " + strconv.Quote(name) + " -> " + strconv.Quote(aDepend.N))
+			if aDepend.V != TagEmptyVersion {
+				buffer.WriteString(" [label=" + strconv.Quote(aDepend.V) + "]")
+			}
+			buffer.WriteString(";\n")
+		}
+	}
+	buffer.WriteString("}\n")
+	return buffer.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart with one class per
+// module state.
+func (s *DependGraph) Mermaid() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("flowchart LR\n")
+	names := s.sortedNames()
+	ids := make(map[string]string, len(names))
+	classes := map[string][]string{}
+	for idx, name := range names {
+		id := "n" + strconv.Itoa(idx)
+		ids[name] = id
+		aNode := s.Nodes[name]
+		buffer.WriteString("  " + id + "[\"" + mermaidEscape(aNode.label()) + "\"]\n")
+		if aNode.State&StateMapPodfileModuleLocal != 0 {
+			classes["local"] = append(classes["local"], id)
+		}
+		if aNode.State&StateMapPodfileModuleImplicit != 0 {
+			classes["implicit"] = append(classes["implicit"], id)
+		}
+		if aNode.State&StateMapPodfileModuleNew != 0 {
+			classes["new"] = append(classes["new"], id)
+		}
+	}
+	for _, name := range names {
+		for _, aDepend := range s.Nodes[name].Depends {
+			buffer.WriteString("  " + ids[name] + " -->")
+			if aDepend.V != TagEmptyVersion {
+				buffer.WriteString("|\"" + mermaidEscape(aDepend.V) + "\"|")
+			}
+			buffer.WriteString(" " + ids[aDepend.N] + "\n")
+		}
+	}
+	styles := [][2]string{
+		{"local", "fill:#add8e6,stroke:#333"},
+		{"implicit", "stroke-dasharray:5 5"},
+		{"new", "stroke:#006400,color:#006400"},
+	}
+	for _, style := range styles {
+		if len(classes[style[0]]) == 0 {
+			continue
+		}
+		buffer.WriteString("  classDef " + style[0] + " " + style[1] + "\n")
+		buffer.WriteString("  class " + strings.Join(classes[style[0]], ",") + " " + style[0] + "\n")
+	}
+	return buffer.String()
+}
+
+// Tree renders the graph as an indented tree like `npm ls`. A module already
+// expanded is marked "(deduped)" and a cycle "(circular)".
+func (s *DependGraph) Tree() string {
+	var buffer bytes.Buffer
+	buffer.WriteString(s.Name + "\n")
+	expanded := make(map[string]bool)
+	var walk func(name, constraint, prefix string, last bool, stack map[string]bool)
+	walk = func(name, constraint, prefix string, last bool, stack map[string]bool) {
+		aNode := s.Nodes[name]
+		branch, indent := "├── ", "│   "
+		if last {
+			branch, indent = "└── ", "    "
+		}
+		line := aNode.label()
+		if constraint != TagEmptyVersion {
+			line += " (" + constraint + ")"
+		}
+		switch {
+		case stack[name]:
+			line += " (circular)"
+		case expanded[name] && len(aNode.Depends) > 0:
+			line += " (deduped)"
+		}
+		buffer.WriteString(prefix + branch + line + "\n")
+		if stack[name] || expanded[name] {
+			return
+		}
+		expanded[name] = true
+		stack[name] = true
+		for idx, aDepend := range aNode.Depends {
+			walk(aDepend.N, aDepend.V, prefix+indent, idx == len(aNode.Depends)-1, stack)
+		}
+		delete(stack, name)
+	}
+	for idx, name := range s.Roots {
+		walk(name, TagEmptyVersion, "", idx == len(s.Roots)-1, make(map[string]bool))
+	}
+	return buffer.String()
+}
+
+func (s *DependGraph) node(name string) *DependGraphNode {
+	aNode, ok := s.Nodes[name]
+	if !ok {
+		aNode = &DependGraphNode{Name: name}
+		s.Nodes[name] = aNode
+	}
+	return aNode
+}
+
+// finish collapses subspecs if asked, then removes duplicated edges and sorts
+// roots and edges so the output is stable.
+func (s *DependGraph) finish(opts *DependGraphOptions) *DependGraph {
+	if opts != nil && opts.CollapseSubspecs {
+		nodes := make(map[string]*DependGraphNode)
+		for name, aNode := range s.Nodes {
+			baseName := fdt.StrSplitFirst(name, "/")
+			aBaseNode, ok := nodes[baseName]
+			if !ok {
+				aBaseNode = &DependGraphNode{Name: baseName}
+				nodes[baseName] = aBaseNode
+			}
+			if aBaseNode.Version == TagEmptyVersion {
+				aBaseNode.Version = aNode.Version
+			}
+			aBaseNode.State |= aNode.State
+			for _, aDepend := range aNode.Depends {
+				aBaseNode.Depends = append(aBaseNode.Depends, &DependBase{N: fdt.StrSplitFirst(aDepend.N, "/"), V: aDepend.V})
+			}
+		}
+		s.Nodes = nodes
+		for idx, name := range s.Roots {
+			s.Roots[idx] = fdt.StrSplitFirst(name, "/")
+		}
+	}
+	for name, aNode := range s.Nodes {
+		edges := make(map[string]string)
+		for _, aDepend := range aNode.Depends {
+			if aDepend.N == name {
+				continue
+			}
+			if v, ok := edges[aDepend.N]; !ok || v == TagEmptyVersion {
+				edges[aDepend.N] = aDepend.V
+			}
+		}
+		aNode.Depends = make([]*DependBase, 0, len(edges))
+		for _, n := range sortedDependNames(edges) {
+			aNode.Depends = append(aNode.Depends, &DependBase{N: n, V: edges[n]})
+		}
+	}
+	roots := make([]string, 0, len(s.Roots))
+	for _, name := range s.Roots {
+		if !fdt.SliceContainsStr(name, roots) {
+			roots = append(roots, name)
+		}
+	}
+	sort.Strings(roots)
+	s.Roots = roots
+	return s
+}
+
+func (s *DependGraph) sortedNames() []string {
+	names := make([]string, 0, len(s.Nodes))
+	for name := range s.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ** DependGraphNode Impl **
+func (s *DependGraphNode) label() string {
+	if s.Version == TagEmptyVersion {
+		return s.Name
+	}
+	return s.Name + " " + s.Version
+}
+
+// ** Func Private **
+func newDependGraph(name string) *DependGraph {
+	return &DependGraph{Name: name, Nodes: make(map[string]*DependGraphNode)}
+}
+
+func sortedDependNames(m map[string]string) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func mermaidEscape(s string) string {
+	return strings.Replace(s, "\"", "#quot;", -1)
+}
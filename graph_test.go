@@ -0,0 +1,91 @@
+package pod
+
+import (
+	"testing"
+)
+
+// newTestGraph has a shared dependency, Shared-Kit, a cycle between Cycle-A
+// and Cycle-B, and names with "/" and "-" that must be quoted in DOT and
+// replaced by ids in Mermaid.
+func newTestGraph() *DependGraph {
+	aGraph := newDependGraph("Pods")
+	aGraph.Roots = []string{"Foo/Core", "Bar"}
+	add := func(name, version string, state uint, depends ...string) {
+		aNode := aGraph.node(name)
+		aNode.Version, aNode.State = version, state
+		for _, d := range depends {
+			n, c := splitTestKey(d)
+			aGraph.node(n)
+			aNode.Depends = append(aNode.Depends, &DependBase{N: n, V: c})
+		}
+	}
+	add("Foo/Core", "1.0", StateMapPodfileModuleLocal, "Shared-Kit ~> 1.0", "Bar")
+	add("Bar", "2.0", 0, "Shared-Kit >= 1.2", "Cycle-A")
+	add("Shared-Kit", "1.3", StateMapPodfileModuleImplicit|StateMapPodfileModuleNew)
+	add("Cycle-A", "1.0", StateMapPodfileModuleImplicit, "Cycle-B")
+	add("Cycle-B", "1.0", StateMapPodfileModuleImplicit, "Cycle-A")
+	return aGraph.finish(nil)
+}
+
+func TestDependGraphDOT(t *testing.T) {
+	want := `digraph "Pods" {
+  rankdir=LR;
+  node [shape=ellipse];
+  "Bar" [label="Bar 2.0"];
+  "Cycle-A" [label="Cycle-A 1.0", style=dashed];
+  "Cycle-B" [label="Cycle-B 1.0", style=dashed];
+  "Foo/Core" [label="Foo/Core 1.0", shape=box, style=filled, fillcolor=lightblue];
+  "Shared-Kit" [label="Shared-Kit 1.3", style=dashed, color=darkgreen, fontcolor=darkgreen];
+  "Bar" -> "Cycle-A";
+  "Bar" -> "Shared-Kit" [label=">= 1.2"];
+  "Cycle-A" -> "Cycle-B";
+  "Cycle-B" -> "Cycle-A";
+  "Foo/Core" -> "Bar";
+  "Foo/Core" -> "Shared-Kit" [label="~> 1.0"];
+}
+`
+	if got := newTestGraph().DOT(); got != want {
+		t.Errorf("DOT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDependGraphMermaid(t *testing.T) {
+	want := `flowchart LR
+  n0["Bar 2.0"]
+  n1["Cycle-A 1.0"]
+  n2["Cycle-B 1.0"]
+  n3["Foo/Core 1.0"]
+  n4["Shared-Kit 1.3"]
+  n0 --> n1
+  n0 -->|">= 1.2"| n4
+  n1 --> n2
+  n2 --> n1
+  n3 --> n0
+  n3 -->|"~> 1.0"| n4
+  classDef local fill:#add8e6,stroke:#333
+  class n3 local
+  classDef implicit stroke-dasharray:5 5
+  class n1,n2,n4 implicit
+  classDef new stroke:#006400,color:#006400
+  class n4 new
+`
+	if got := newTestGraph().Mermaid(); got != want {
+		t.Errorf("Mermaid() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDependGraphTree(t *testing.T) {
+	want := `Pods
+├── Bar 2.0
+│   ├── Cycle-A 1.0
+│   │   └── Cycle-B 1.0
+│   │       └── Cycle-A 1.0 (circular)
+│   └── Shared-Kit 1.3 (>= 1.2)
+└── Foo/Core 1.0
+    ├── Bar 2.0 (deduped)
+    └── Shared-Kit 1.3 (~> 1.0)
+`
+	if got := newTestGraph().Tree(); got != want {
+		t.Errorf("Tree() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,27 @@
+package pod
+
+// DependGraph is a dependency graph ready to render. Roots are the nodes the
+// graph starts from, the Podfile modules or the requested spec.
+type DependGraph struct {
+	Name  string
+	Roots []string
+	Nodes map[string]*DependGraphNode
+}
+
+// DependGraphNode is a module of a DependGraph. State takes the
+// StateMapPodfileModule* flags and Depends are the edges, labeled by their
+// constraint.
+type DependGraphNode struct {
+	Name    string
+	Version string
+	State   uint
+	Depends []*DependBase
+}
+
+// DependGraphOptions controls how a DependGraph is built.
+// CollapseSubspecs merges every subspec into the node of its pod, Platform
+// selects the platform-scoped dependencies of a spec.
+type DependGraphOptions struct {
+	CollapseSubspecs bool
+	Platform         string
+}
@@ -0,0 +1,291 @@
+package pod
+
+import (
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	fdt "github.com/go-hayden-base/foundation"
+	ver "github.com/go-hayden-base/version"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var regLockfileEntry = regexp.MustCompile(`^(\S+)(?: \((.*)\))?$`)
+var regLockfileRequirement = regexp.MustCompile(`^(=|!=|>=|<=|>|<|~>)?\s*(\S+)$`)
+
+// ** Lockfile Impl **
+func (s *Lockfile) PodWithName(name string) *LockfilePod {
+	for _, aPod := range s.Pods {
+		if aPod.N == name {
+			return aPod
+		}
+	}
+	return nil
+}
+
+// VersionOfModule returns the locked version of module. Subspecs share the
+// version of their root pod, so "Foo/Core" is found through "Foo" as well.
+func (s *Lockfile) VersionOfModule(name string) (string, bool) {
+	if aPod := s.PodWithName(name); aPod != nil {
+		return aPod.V, true
+	}
+	baseName := fdt.StrSplitFirst(name, "/")
+	for _, aPod := range s.Pods {
+		if fdt.StrSplitFirst(aPod.N, "/") == baseName {
+			return aPod.V, true
+		}
+	}
+	return "", false
+}
+
+func (s *Lockfile) DependencyWithName(name string) *DependBase {
+	for _, aDepend := range s.Dependencies {
+		if aDepend.N == name {
+			return aDepend
+		}
+	}
+	return nil
+}
+
+// SpecRepoOfModule returns the source repo that the root pod of module was
+// installed from.
+func (s *Lockfile) SpecRepoOfModule(name string) (string, bool) {
+	baseName := fdt.StrSplitFirst(name, "/")
+	for repo, modules := range s.SpecRepos {
+		if fdt.SliceContainsStr(baseName, modules) {
+			return repo, true
+		}
+	}
+	return "", false
+}
+
+func (s *Lockfile) IsExternal(name string) bool {
+	_, ok := s.ExternalSources[fdt.StrSplitFirst(name, "/")]
+	return ok
+}
+
+// DiffPodfile reports the modules of aPodfile that are not locked (Added),
+// the locked dependencies no longer declared (Removed) and the modules whose
+// requirements changed or are not satisfied by the locked version (Changed).
+// Requirements are compared as Podfile.lock writes them, so '1.0' equals
+// (= 1.0) and ['< 2.0', '>= 1.0'] equals (>= 1.0, < 2.0).
+func (s *Lockfile) DiffPodfile(aPodfile *Podfile) *LockfileDiff {
+	diff := new(LockfileDiff)
+	if aPodfile == nil {
+		return diff
+	}
+	declared := make(map[string]bool)
+	for _, aTarget := range aPodfile.Targets {
+		for _, aModule := range aTarget.Modules {
+			if declared[aModule.N] {
+				continue
+			}
+			declared[aModule.N] = true
+			locked, ok := s.VersionOfModule(aModule.N)
+			if !ok {
+				diff.Added = append(diff.Added, aModule.N)
+				continue
+			}
+			if aModule.IsLocal() || s.IsExternal(aModule.N) {
+				continue
+			}
+			requirements := aModule.VersionRequirements()
+			if aDepend := s.DependencyWithName(aModule.N); aDepend != nil && lockfileRequirement([]string{aDepend.V}) != lockfileRequirement(requirements) {
+				diff.Changed = append(diff.Changed, aModule.N)
+				continue
+			}
+			for _, r := range requirements {
+				if ver.IsVersionConstraint(r) && !ver.MatchVersionConstraint(r, locked) {
+					diff.Changed = append(diff.Changed, aModule.N)
+					break
+				}
+			}
+		}
+	}
+	for _, aDepend := range s.Dependencies {
+		if !declared[aDepend.N] {
+			diff.Removed = append(diff.Removed, aDepend.N)
+		}
+	}
+	diff.sort()
+	return diff
+}
+
+// DiffLockfile compares the receiver (usually Podfile.lock) with other
+// (usually Pods/Manifest.lock). Added pods are locked but not installed,
+// Removed pods are installed but no longer locked.
+func (s *Lockfile) DiffLockfile(other *Lockfile) *LockfileDiff {
+	diff := new(LockfileDiff)
+	if other == nil {
+		for _, aPod := range s.Pods {
+			diff.Added = append(diff.Added, aPod.N)
+		}
+		return diff
+	}
+	for _, aPod := range s.Pods {
+		aOtherPod := other.PodWithName(aPod.N)
+		if aOtherPod == nil {
+			diff.Added = append(diff.Added, aPod.N)
+			continue
+		}
+		if aPod.V != aOtherPod.V {
+			diff.Changed = append(diff.Changed, aPod.N)
+			continue
+		}
+		baseName := fdt.StrSplitFirst(aPod.N, "/")
+		if baseName == aPod.N && s.SpecChecksums[baseName] != other.SpecChecksums[baseName] {
+			diff.Changed = append(diff.Changed, aPod.N)
+		}
+	}
+	for _, aOtherPod := range other.Pods {
+		if s.PodWithName(aOtherPod.N) == nil {
+			diff.Removed = append(diff.Removed, aOtherPod.N)
+		}
+	}
+	diff.sort()
+	return diff
+}
+
+// ** LockfilePod Impl **
+func (s *LockfilePod) Subdepends() []*DependBase {
+	return s.Depends
+}
+
+// ** LockfileDiff Impl **
+func (s *LockfileDiff) IsEmpty() bool {
+	return len(s.Added) == 0 && len(s.Removed) == 0 && len(s.Changed) == 0
+}
+
+func (s *LockfileDiff) sort() {
+	sort.Strings(s.Added)
+	sort.Strings(s.Removed)
+	sort.Strings(s.Changed)
+}
+
+// ** Func Public **
+func NewLockfile(filePath string) (*Lockfile, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	aLockfile, err := NewLockfileWithBytes(b)
+	if err != nil {
+		return nil, err
+	}
+	aLockfile.FilePath = filePath
+	return aLockfile, nil
+}
+
+func NewLockfileWithBytes(b []byte) (*Lockfile, error) {
+	var lf *p_lockfile
+	if err := yaml.Unmarshal(b, &lf); err != nil {
+		return nil, err
+	}
+	if lf == nil {
+		return nil, errors.New("Lockfile 内容为空！")
+	}
+	aLockfile := new(Lockfile)
+	aLockfile.Pods = make([]*LockfilePod, 0, len(lf.Pods))
+	for _, item := range lf.Pods {
+		aPod, err := parseLockfilePod(item)
+		if err != nil {
+			return nil, err
+		}
+		aLockfile.Pods = append(aLockfile.Pods, aPod)
+	}
+	aLockfile.Dependencies = make([]*DependBase, 0, len(lf.Dependencies))
+	for _, item := range lf.Dependencies {
+		aDepend, err := parseLockfileEntry(item)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(aDepend.V, "from `") {
+			aDepend.V = TagEmptyVersion
+		}
+		aLockfile.Dependencies = append(aLockfile.Dependencies, aDepend)
+	}
+	aLockfile.SpecRepos = lf.SpecRepos
+	aLockfile.ExternalSources = lf.ExternalSources
+	aLockfile.CheckoutOptions = lf.CheckoutOptions
+	aLockfile.SpecChecksums = lf.SpecChecksums
+	aLockfile.PodfileChecksum = lf.PodfileChecksum
+	aLockfile.CocoaPodsVersion = lf.Cocoapods
+	return aLockfile, nil
+}
+
+// ** Func Private **
+func parseLockfilePod(item interface{}) (*LockfilePod, error) {
+	if s, ok := item.(string); ok {
+		aDepend, err := parseLockfileEntry(s)
+		if err != nil {
+			return nil, err
+		}
+		return &LockfilePod{DependBase: *aDepend}, nil
+	}
+	m, ok := item.(map[interface{}]interface{})
+	if !ok || len(m) != 1 {
+		return nil, errors.New("无法解析Lockfile中的PODS条目！")
+	}
+	for k, v := range m {
+		ks, ok := k.(string)
+		if !ok {
+			return nil, errors.New("无法解析Lockfile中的PODS条目！")
+		}
+		aDepend, err := parseLockfileEntry(ks)
+		if err != nil {
+			return nil, err
+		}
+		aPod := &LockfilePod{DependBase: *aDepend}
+		subs, _ := v.([]interface{})
+		for _, sub := range subs {
+			ss, ok := sub.(string)
+			if !ok {
+				return nil, errors.New("无法解析 " + aPod.N + " 的依赖！")
+			}
+			aSubDepend, err := parseLockfileEntry(ss)
+			if err != nil {
+				return nil, err
+			}
+			aPod.Depends = append(aPod.Depends, aSubDepend)
+		}
+		return aPod, nil
+	}
+	return nil, nil
+}
+
+// lockfileRequirement joins requirements the way Podfile.lock writes them:
+// each with its operator, = when it has none, sorted and separated by ", ".
+// A requirement may itself be a list read from a lockfile.
+func lockfileRequirement(requirements []string) string {
+	items := make([]string, 0, len(requirements))
+	for _, r := range requirements {
+		for _, item := range strings.Split(r, ",") {
+			item = strings.TrimSpace(item)
+			if item == TagEmptyVersion || item == TagUnknownVersion {
+				continue
+			}
+			if match := regLockfileRequirement.FindStringSubmatch(item); match != nil {
+				op := match[1]
+				if op == "" {
+					op = "="
+				}
+				item = op + " " + match[2]
+			}
+			if !fdt.SliceContainsStr(item, items) {
+				items = append(items, item)
+			}
+		}
+	}
+	sort.Strings(items)
+	return strings.Join(items, ", ")
+}
+
+func parseLockfileEntry(entry string) (*DependBase, error) {
+	match := regLockfileEntry.FindStringSubmatch(strings.TrimSpace(entry))
+	if match == nil {
+		return nil, errors.New("无法解析Lockfile条目: " + entry)
+	}
+	return &DependBase{N: match[1], V: match[2]}, nil
+}
@@ -0,0 +1,223 @@
+package pod
+
+import (
+	"strings"
+	"testing"
+)
+
+const testManifestLockfile = `PODS:
+  - AFNetworking (3.2.1):
+    - AFNetworking/NSURLSession (= 3.2.1)
+  - AFNetworking/NSURLSession (3.2.1)
+  - Local (0.1)
+  - Remote (2.0)
+
+DEPENDENCIES:
+  - AFNetworking (~> 3.0)
+  - Local (from ` + "`../Local`" + `)
+  - Remote (from ` + "`https://example.com/Remote.git`" + `, tag ` + "`2.0`" + `)
+
+SPEC REPOS:
+  trunk:
+    - AFNetworking
+
+EXTERNAL SOURCES:
+  Local:
+    :path: "../Local"
+  Remote:
+    :git: https://example.com/Remote.git
+    :tag: '2.0'
+
+CHECKOUT OPTIONS:
+  Remote:
+    :git: https://example.com/Remote.git
+    :tag: '2.0'
+
+SPEC CHECKSUMS:
+  AFNetworking: b6f891fdfaed196b46c7a83cf209e09697b94057
+  Local: 1111111111111111111111111111111111111111
+  Remote: 2222222222222222222222222222222222222222
+
+PODFILE CHECKSUM: 3333333333333333333333333333333333333333
+
+COCOAPODS: 1.12.1
+`
+
+func TestNewLockfileWithBytes(t *testing.T) {
+	aLockfile, err := NewLockfileWithBytes([]byte(testManifestLockfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	version := func(name string) string {
+		v, _ := aLockfile.VersionOfModule(name)
+		return v
+	}
+	repo := func(name string) string {
+		r, _ := aLockfile.SpecRepoOfModule(name)
+		return r
+	}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "pods", got: lockfilePodNames(aLockfile), want: "AFNetworking,AFNetworking/NSURLSession,Local,Remote"},
+		{name: "subdepends", got: dependString(aLockfile.PodWithName("AFNetworking").Subdepends()), want: "AFNetworking/NSURLSession (= 3.2.1)"},
+		{name: "version", got: version("AFNetworking"), want: "3.2.1"},
+		{name: "subspec version", got: version("AFNetworking/UIKit"), want: "3.2.1"},
+		{name: "dependency", got: aLockfile.DependencyWithName("AFNetworking").V, want: "~> 3.0"},
+		{name: "local dependency", got: aLockfile.DependencyWithName("Local").V, want: ""},
+		{name: "spec repo", got: repo("AFNetworking/NSURLSession"), want: "trunk"},
+		{name: "checkout", got: aLockfile.CheckoutOptions["Remote"][":tag"], want: "2.0"},
+		{name: "checksum", got: aLockfile.SpecChecksums["Local"], want: "1111111111111111111111111111111111111111"},
+		{name: "podfile checksum", got: aLockfile.PodfileChecksum, want: "3333333333333333333333333333333333333333"},
+		{name: "cocoapods", got: aLockfile.CocoaPodsVersion, want: "1.12.1"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+	if !aLockfile.IsExternal("Remote") || aLockfile.IsExternal("AFNetworking") {
+		t.Error("IsExternal() does not match EXTERNAL SOURCES")
+	}
+}
+
+func TestNewLockfileWithBytesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "empty", source: ""},
+		{name: "not yaml", source: "PODS: [\n"},
+		{name: "bad pod entry", source: "PODS:\n  - Foo (1.0):\n    - {a: b}\n"},
+	}
+	for _, tt := range tests {
+		if _, err := NewLockfileWithBytes([]byte(tt.source)); err == nil {
+			t.Errorf("%s: want an error", tt.name)
+		}
+	}
+}
+
+func TestLockfileDiffLockfile(t *testing.T) {
+	aLockfile, err := NewLockfileWithBytes([]byte(testManifestLockfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := strings.Replace(testManifestLockfile, "  - Remote (2.0)\n", "  - Remote (1.0)\n  - Gone (1.0)\n", 1)
+	manifest = strings.Replace(manifest, "Local: 1111111111111111111111111111111111111111", "Local: 4444444444444444444444444444444444444444", 1)
+	manifest = strings.Replace(manifest, "  - AFNetworking/NSURLSession (3.2.1)\n", "", 1)
+	aManifest, err := NewLockfileWithBytes([]byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := aLockfile.DiffLockfile(aManifest)
+	tests := []struct {
+		name string
+		got  []string
+		want string
+	}{
+		{name: "Added", got: diff.Added, want: "AFNetworking/NSURLSession"},
+		{name: "Removed", got: diff.Removed, want: "Gone"},
+		{name: "Changed", got: diff.Changed, want: "Local,Remote"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(tt.got, ","); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+	if diff := aLockfile.DiffLockfile(aLockfile); !diff.IsEmpty() {
+		t.Errorf("DiffLockfile() with itself = %+v, want no difference", diff)
+	}
+}
+
+const testLockfile = `PODS:
+  - Bar (1.5)
+  - Baz (1.2)
+  - Foo (1.0)
+  - Local (0.1)
+  - Old (1.0)
+  - Qux (2.0)
+
+DEPENDENCIES:
+  - Bar (< 2.0, >= 1.0)
+  - Baz (< 2.0, >= 1.0)
+  - Foo (= 1.0)
+  - Local (from ` + "`../Local`" + `)
+  - Old
+  - Qux
+
+EXTERNAL SOURCES:
+  Local:
+    :path: "../Local"
+
+COCOAPODS: 1.12.1
+`
+
+func TestLockfileDiffPodfile(t *testing.T) {
+	aLockfile, err := NewLockfileWithBytes([]byte(testLockfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte(`target 'App' do
+  pod 'Bar', '>= 1.0', '< 2.0'
+  pod 'Baz', '>= 1.0', '< 3.0'
+  pod 'Foo', '1.0'
+  pod 'Local', :path => '../Local'
+  pod 'New', '~> 1.0'
+  pod 'Qux'
+end
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := aLockfile.DiffPodfile(aPodfile)
+	tests := []struct {
+		name string
+		got  []string
+		want string
+	}{
+		{name: "Added", got: diff.Added, want: "New"},
+		{name: "Removed", got: diff.Removed, want: "Old"},
+		{name: "Changed", got: diff.Changed, want: "Baz"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(tt.got, ","); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLockfileRequirement(t *testing.T) {
+	tests := []struct {
+		requirements []string
+		want         string
+	}{
+		{nil, ""},
+		{[]string{"1.0"}, "= 1.0"},
+		{[]string{">= 1.0", "< 2.0"}, "< 2.0, >= 1.0"},
+		{[]string{"< 2.0, >= 1.0"}, "< 2.0, >= 1.0"},
+		{[]string{"~>1.2", "!= 1.2.1"}, "!= 1.2.1, ~> 1.2"},
+	}
+	for _, tt := range tests {
+		if got := lockfileRequirement(tt.requirements); got != tt.want {
+			t.Errorf("lockfileRequirement(%q) = %q, want %q", tt.requirements, got, tt.want)
+		}
+	}
+}
+
+func lockfilePodNames(aLockfile *Lockfile) string {
+	names := make([]string, 0, len(aLockfile.Pods))
+	for _, aPod := range aLockfile.Pods {
+		names = append(names, aPod.N)
+	}
+	return strings.Join(names, ",")
+}
+
+func dependString(depends []*DependBase) string {
+	items := make([]string, 0, len(depends))
+	for _, d := range depends {
+		items = append(items, d.N+" ("+d.V+")")
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,48 @@
+package pod
+
+type Lockfile struct {
+	FilePath         string
+	Pods             []*LockfilePod
+	Dependencies     []*DependBase
+	SpecRepos        map[string][]string
+	ExternalSources  map[string]map[string]string
+	CheckoutOptions  map[string]map[string]string
+	SpecChecksums    map[string]string
+	PodfileChecksum  string
+	CocoaPodsVersion string
+}
+
+// LockfilePod is an entry of PODS, V is the installed version and the
+// versions of Depends are requirements such as "= 3.2.1".
+type LockfilePod struct {
+	DependBase
+	Depends []*DependBase
+}
+
+// LockfileOptions completes what a MapPodfile does not know when a Lockfile
+// is generated from it. Pod finds the spec repo and spec file of each pod,
+// RepoURLs maps a repo name to the source URL written in SPEC REPOS.
+type LockfileOptions struct {
+	Pod              *Pod
+	RepoURLs         map[string]string
+	PodfileChecksum  string
+	CocoaPodsVersion string
+}
+
+type LockfileDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// *** Private ***
+type p_lockfile struct {
+	Pods            []interface{}                `yaml:"PODS"`
+	Dependencies    []string                     `yaml:"DEPENDENCIES"`
+	SpecRepos       map[string][]string          `yaml:"SPEC REPOS"`
+	ExternalSources map[string]map[string]string `yaml:"EXTERNAL SOURCES"`
+	CheckoutOptions map[string]map[string]string `yaml:"CHECKOUT OPTIONS"`
+	SpecChecksums   map[string]string            `yaml:"SPEC CHECKSUMS"`
+	PodfileChecksum string                       `yaml:"PODFILE CHECKSUM"`
+	Cocoapods       string                       `yaml:"COCOAPODS"`
+}
@@ -0,0 +1,330 @@
+package pod
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	fdt "github.com/go-hayden-base/foundation"
+)
+
+var regYAMLPlain = regexp.MustCompile(`^[A-Za-z0-9_][^\s#'"]*$`)
+
+// ** Lockfile Writer **
+
+// NewLockfileWithMapPodfile builds a Podfile.lock for the resolution of
+// aMapPodfile. DEPENDENCIES come from the modules of target in aPodfile, or
+// of every target when target is empty, with all their requirements, a
+// target the Podfile does not define is an error. Local and git modules go
+// to EXTERNAL SOURCES, the others to SPEC REPOS when opts.Pod knows their
+// repo. SPEC CHECKSUMS are the SHA1 of the spec files, as CocoaPods computes
+// them.
+func NewLockfileWithMapPodfile(aMapPodfile *MapPodfile, aPodfile *Podfile, target string, opts *LockfileOptions) (*Lockfile, error) {
+	if aMapPodfile == nil || aPodfile == nil {
+		return nil, errors.New("Argement aMapPodfile or aPodfile is nil")
+	}
+	if opts == nil {
+		opts = new(LockfileOptions)
+	}
+	var only *PodfileTarget
+	if target != "" {
+		if only = aPodfile.TargetWithName(target); only == nil {
+			return nil, errors.New("Podfile中没有target: " + target)
+		}
+	}
+	declared := make([]*PodfileModule, 0, 10)
+	dup := make(map[string]bool)
+	for _, aTarget := range aPodfile.Targets {
+		if only != nil && aTarget != only {
+			continue
+		}
+		for _, aModule := range aTarget.EffectiveModules() {
+			if !dup[aModule.N] {
+				dup[aModule.N] = true
+				declared = append(declared, aModule)
+			}
+		}
+	}
+
+	aLockfile := new(Lockfile)
+	aLockfile.PodfileChecksum = opts.PodfileChecksum
+	aLockfile.CocoaPodsVersion = opts.CocoaPodsVersion
+	aLockfile.SpecRepos = make(map[string][]string)
+	aLockfile.ExternalSources = make(map[string]map[string]string)
+	aLockfile.CheckoutOptions = make(map[string]map[string]string)
+	aLockfile.SpecChecksums = make(map[string]string)
+
+	unresolved := make([]string, 0)
+	for _, aModule := range aMapPodfile.Map {
+		if aModule.UsefulV == TagEmptyVersion || aModule.UsefulV == TagUnknownVersion {
+			unresolved = append(unresolved, aModule.Name)
+			continue
+		}
+		aPod := &LockfilePod{DependBase: DependBase{N: aModule.Name, V: aModule.UsefulV}}
+		depends, _ := aModule.Depends()
+		for _, aDepend := range depends {
+			aPod.Depends = append(aPod.Depends, &DependBase{N: aDepend.N, V: aDepend.V})
+		}
+		sortDepends(aPod.Depends)
+		aLockfile.Pods = append(aLockfile.Pods, aPod)
+	}
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		return nil, errors.New("以下模块没有确定版本: " + strings.Join(unresolved, ", "))
+	}
+	sort.Slice(aLockfile.Pods, func(i, j int) bool {
+		return lessFold(aLockfile.Pods[i].N, aLockfile.Pods[j].N)
+	})
+
+	externals := make(map[string]*PodfileModule)
+	for _, aModule := range declared {
+		aDepend := &DependBase{N: aModule.N, V: lockfileRequirement(aModule.VersionRequirements())}
+		if source := externalSourceOf(aModule); source != nil {
+			aDepend.V = TagEmptyVersion
+			baseName := fdt.StrSplitFirst(aModule.N, "/")
+			aLockfile.ExternalSources[baseName] = source
+			externals[baseName] = aModule
+			if aModule.Options != nil && aModule.Options.Git != "" && aModule.Options.Commit != "" {
+				aLockfile.CheckoutOptions[baseName] = map[string]string{":git": aModule.Options.Git, ":commit": aModule.Options.Commit}
+			}
+		}
+		aLockfile.Dependencies = append(aLockfile.Dependencies, aDepend)
+	}
+	sortDepends(aLockfile.Dependencies)
+
+	podfileDir := path.Dir(aPodfile.FilePath)
+	for _, aPod := range aLockfile.Pods {
+		baseName := fdt.StrSplitFirst(aPod.N, "/")
+		if _, ok := aLockfile.SpecChecksums[baseName]; ok {
+			continue
+		}
+		specFile := ""
+		if aModule, ok := externals[baseName]; ok {
+			specFile, _ = aModule.LocalSpecFile(podfileDir)
+		} else if opts.Pod != nil {
+			if repo := opts.Pod.RepoOfModuleVersion(baseName, aPod.V); repo != nil {
+				url := repo.Name
+				if u, ok := opts.RepoURLs[repo.Name]; ok {
+					url = u
+				}
+				if !fdt.SliceContainsStr(baseName, aLockfile.SpecRepos[url]) {
+					aLockfile.SpecRepos[url] = append(aLockfile.SpecRepos[url], baseName)
+				}
+			}
+			if v := opts.Pod.ModuleVersion(baseName, aPod.V); v != nil {
+				specFile = path.Join(v.Root, v.FileName)
+			}
+		}
+		if specFile == "" {
+			continue
+		}
+		if b, err := ioutil.ReadFile(specFile); err == nil {
+			sum := sha1.Sum(b)
+			aLockfile.SpecChecksums[baseName] = hex.EncodeToString(sum[:])
+		}
+	}
+	for url := range aLockfile.SpecRepos {
+		sort.Slice(aLockfile.SpecRepos[url], func(i, j int) bool {
+			return lessFold(aLockfile.SpecRepos[url][i], aLockfile.SpecRepos[url][j])
+		})
+	}
+	return aLockfile, nil
+}
+
+// Bytes renders the Lockfile in the YAML layout written by CocoaPods.
+func (s *Lockfile) Bytes() []byte {
+	var buffer bytes.Buffer
+	section := func(title string) {
+		if buffer.Len() > 0 {
+			buffer.WriteString("\n")
+		}
+		buffer.WriteString(title + ":")
+	}
+
+	section("PODS")
+	buffer.WriteString("\n")
+	for _, aPod := range s.Pods {
+		buffer.WriteString("  - " + lockfileEntry(&aPod.DependBase))
+		if len(aPod.Depends) == 0 {
+			buffer.WriteString("\n")
+			continue
+		}
+		buffer.WriteString(":\n")
+		for _, aDepend := range aPod.Depends {
+			buffer.WriteString("    - " + lockfileEntry(aDepend) + "\n")
+		}
+	}
+
+	section("DEPENDENCIES")
+	buffer.WriteString("\n")
+	for _, aDepend := range s.Dependencies {
+		entry := lockfileEntry(aDepend)
+		if source, ok := s.ExternalSources[fdt.StrSplitFirst(aDepend.N, "/")]; ok && aDepend.V == TagEmptyVersion {
+			entry = aDepend.N + " (" + externalSourceDescription(source) + ")"
+		}
+		buffer.WriteString("  - " + entry + "\n")
+	}
+
+	if len(s.SpecRepos) > 0 {
+		section("SPEC REPOS")
+		buffer.WriteString("\n")
+		for _, url := range sortedKeys(s.SpecRepos) {
+			buffer.WriteString("  " + yamlScalar(url) + ":\n")
+			for _, name := range s.SpecRepos[url] {
+				buffer.WriteString("    - " + yamlScalar(name) + "\n")
+			}
+		}
+	}
+	writeNested := func(title string, m map[string]map[string]string) {
+		if len(m) == 0 {
+			return
+		}
+		section(title)
+		buffer.WriteString("\n")
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			buffer.WriteString("  " + yamlScalar(name) + ":\n")
+			keys := make([]string, 0, len(m[name]))
+			for key := range m[name] {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				buffer.WriteString("    " + key + ": " + yamlScalar(m[name][key]) + "\n")
+			}
+		}
+	}
+	writeNested("EXTERNAL SOURCES", s.ExternalSources)
+	writeNested("CHECKOUT OPTIONS", s.CheckoutOptions)
+
+	if len(s.SpecChecksums) > 0 {
+		section("SPEC CHECKSUMS")
+		buffer.WriteString("\n")
+		names := make([]string, 0, len(s.SpecChecksums))
+		for name := range s.SpecChecksums {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool { return lessFold(names[i], names[j]) })
+		for _, name := range names {
+			buffer.WriteString("  " + yamlScalar(name) + ": " + yamlScalar(s.SpecChecksums[name]) + "\n")
+		}
+	}
+	if s.PodfileChecksum != "" {
+		section("PODFILE CHECKSUM")
+		buffer.WriteString(" " + yamlScalar(s.PodfileChecksum) + "\n")
+	}
+	if s.CocoaPodsVersion != "" {
+		section("COCOAPODS")
+		buffer.WriteString(" " + yamlScalar(s.CocoaPodsVersion) + "\n")
+	}
+	return buffer.Bytes()
+}
+
+// Save writes the Lockfile to filePath, or to FilePath when filePath is
+// empty.
+func (s *Lockfile) Save(filePath string) error {
+	if filePath == "" {
+		filePath = s.FilePath
+	}
+	if filePath == "" {
+		return errors.New("没有指定Lockfile路径！")
+	}
+	return ioutil.WriteFile(filePath, s.Bytes(), 0644)
+}
+
+// ** Func Private **
+func externalSourceOf(aModule *PodfileModule) map[string]string {
+	opts := aModule.Options
+	if opts == nil {
+		if aModule.IsLocal() {
+			return map[string]string{":" + strings.TrimPrefix(aModule.Type, ":"): aModule.SpecPath}
+		}
+		return nil
+	}
+	res := make(map[string]string)
+	switch {
+	case opts.Path != "":
+		res[":path"] = opts.Path
+	case opts.Podspec != "":
+		res[":podspec"] = opts.Podspec
+	case opts.Git != "":
+		res[":git"] = opts.Git
+		for _, kv := range [][2]string{{":branch", opts.Branch}, {":tag", opts.Tag}, {":commit", opts.Commit}} {
+			if kv[1] != "" {
+				res[kv[0]] = kv[1]
+			}
+		}
+	default:
+		return nil
+	}
+	return res
+}
+
+// externalSourceDescription renders a source like CocoaPods does in
+// DEPENDENCIES, e.g. "from `https://x/Foo.git`, tag `1.0`".
+func externalSourceDescription(source map[string]string) string {
+	for _, key := range []string{":path", ":podspec", ":git"} {
+		v, ok := source[key]
+		if !ok {
+			continue
+		}
+		desc := "from `" + v + "`"
+		if key == ":git" {
+			for _, ref := range []string{":branch", ":tag", ":commit"} {
+				if rv, ok := source[ref]; ok {
+					desc += ", " + strings.TrimPrefix(ref, ":") + " `" + rv + "`"
+				}
+			}
+		}
+		return desc
+	}
+	return "from ``"
+}
+
+func lockfileEntry(aDepend *DependBase) string {
+	if aDepend.V == TagEmptyVersion {
+		return aDepend.N
+	}
+	return aDepend.N + " (" + aDepend.V + ")"
+}
+
+func yamlScalar(s string) string {
+	if regYAMLPlain.MatchString(s) && !strings.Contains(s, ": ") && !strings.HasSuffix(s, ":") {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+func sortDepends(depends []*DependBase) {
+	sort.Slice(depends, func(i, j int) bool {
+		return lessFold(depends[i].N, depends[j].N)
+	})
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func lessFold(a, b string) bool {
+	la, lb := strings.ToLower(a), strings.ToLower(b)
+	if la == lb {
+		return a < b
+	}
+	return la < lb
+}
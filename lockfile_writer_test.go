@@ -0,0 +1,184 @@
+package pod
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestNewLockfileWithMapPodfile(t *testing.T) {
+	barSpec := `{"name": "Bar", "version": "1.5"}`
+	fooSpec := `{"name": "Foo", "version": "1.0", "dependencies": {"Bar": ["~> 1.0"]}}`
+	aPod := newTestPod(t, map[string]string{
+		"Specs/Bar/1.5/Bar.podspec.json": barSpec,
+		"Specs/Foo/1.0/Foo.podspec.json": fooSpec,
+	})
+	index := testIndex{"Bar 1.5": nil, "Foo 1.0": {"Bar ~> 1.0"}, "Git 1.0": nil}
+	source := "target 'App' do\n  pod 'Foo', '1.0'\n  pod 'Git', :git => 'https://x/Git.git', :commit => 'abc'\nend\n"
+	aPodfile, err := NewPodfileWithBytes(path.Join(t.TempDir(), "Podfile"), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aMapPodfile, err := NewMapPodfile(aPodfile, "App", nil, index.queryVersion, index.queryDepends)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aMapPodfile.Evolution(nil); err != nil {
+		t.Fatal(err)
+	}
+	opts := &LockfileOptions{
+		Pod:              aPod,
+		RepoURLs:         map[string]string{"test": "https://x/Specs.git"},
+		PodfileChecksum:  "0123",
+		CocoaPodsVersion: "1.12.1",
+	}
+	aLockfile, err := NewLockfileWithMapPodfile(aMapPodfile, aPodfile, "App", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `PODS:
+  - Bar (1.5)
+  - Foo (1.0):
+    - Bar (~> 1.0)
+  - Git (1.0)
+
+DEPENDENCIES:
+  - Foo (= 1.0)
+  - Git (from ` + "`https://x/Git.git`, commit `abc`" + `)
+
+SPEC REPOS:
+  https://x/Specs.git:
+    - Bar
+    - Foo
+
+EXTERNAL SOURCES:
+  Git:
+    :commit: abc
+    :git: https://x/Git.git
+
+CHECKOUT OPTIONS:
+  Git:
+    :commit: abc
+    :git: https://x/Git.git
+
+SPEC CHECKSUMS:
+  Bar: ` + testSHA1(barSpec) + `
+  Foo: ` + testSHA1(fooSpec) + `
+
+PODFILE CHECKSUM: 0123
+
+COCOAPODS: 1.12.1
+`
+	b := aLockfile.Bytes()
+	if got := string(b); got != want {
+		t.Errorf("Bytes() =\n%s\nwant\n%s", got, want)
+	}
+	aReadLockfile, err := NewLockfileWithBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(aReadLockfile.Bytes()); got != want {
+		t.Errorf("Bytes() after reading =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNewLockfileWithMapPodfileUnresolved(t *testing.T) {
+	index := testIndex{"Foo 1.0": nil}
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte("target 'App' do\n  pod 'Foo'\n  pod 'Missing'\nend\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aMapPodfile, err := NewMapPodfile(aPodfile, "App", nil, index.queryVersion, index.queryDepends)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aMapPodfile.Evolution(nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewLockfileWithMapPodfile(aMapPodfile, aPodfile, "App", nil)
+	if err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Errorf("NewLockfileWithMapPodfile() error = %v, want one naming Missing", err)
+	}
+}
+
+func TestNewLockfileWithMapPodfileRequirements(t *testing.T) {
+	index := testIndex{"Bar 1.0": nil, "Bar 1.5": nil, "Bar 2.0": nil, "Foo 1.0": {"Bar ~> 1.0"}}
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte("target 'App' do\n  pod 'Bar', '>= 1.0', '< 2.0'\n  pod 'Foo', '1.0'\nend\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aMapPodfile, err := NewMapPodfile(aPodfile, "App", nil, index.queryVersion, index.queryDepends)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aMapPodfile.Evolution(nil); err != nil {
+		t.Fatal(err)
+	}
+	aLockfile, err := NewLockfileWithMapPodfile(aMapPodfile, aPodfile, "App", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "PODS:\n  - Bar (1.5)\n  - Foo (1.0):\n    - Bar (~> 1.0)\n\nDEPENDENCIES:\n  - Bar (< 2.0, >= 1.0)\n  - Foo (= 1.0)\n"
+	b := aLockfile.Bytes()
+	if got := string(b); got != want {
+		t.Errorf("Bytes() =\n%s\nwant\n%s", got, want)
+	}
+	aReadLockfile, err := NewLockfileWithBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := aReadLockfile.DiffPodfile(aPodfile); !diff.IsEmpty() {
+		t.Errorf("DiffPodfile() = %+v, want no difference", diff)
+	}
+}
+
+func TestNewLockfileWithMapPodfileTarget(t *testing.T) {
+	index := testIndex{"Root 1.0": nil, "Foo 1.0": nil}
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte("pod 'Root'\ntarget 'App' do\n  pod 'Foo'\nend\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aMapPodfile, err := NewMapPodfile(aPodfile, "App", nil, index.queryVersion, index.queryDepends)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aMapPodfile.Evolution(nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		target  string
+		want    string
+		wantErr bool
+	}{
+		{target: "", want: "Foo,Root"},
+		{target: "App", want: "Foo,Root"},
+		{target: PodfileRootTargetName, want: "Root"},
+		{target: "Nope", wantErr: true},
+	}
+	for _, tt := range tests {
+		aLockfile, err := NewLockfileWithMapPodfile(aMapPodfile, aPodfile, tt.target, nil)
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), tt.target) {
+				t.Errorf("%q: error = %v, want one naming the target", tt.target, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(aLockfile.Dependencies))
+		for _, aDepend := range aLockfile.Dependencies {
+			names = append(names, aDepend.N)
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("%q: DEPENDENCIES = %s, want %s", tt.target, got, tt.want)
+		}
+	}
+}
+
+func testSHA1(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,77 @@
+package pod
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMapPodfileEvolutionErrors(t *testing.T) {
+	// The newest A and B exclude each other's newest version, and the older
+	// ones need the newest: every iteration flips both
+	index := testIndex{
+		"A 1.0": {"B >= 2.0"}, "A 2.0": {"B < 2.0"},
+		"B 1.0": {"A >= 2.0"}, "B 2.0": {"A < 2.0"},
+		"C 1.0": {"D ~> 1.0"},
+		"D 1.0": nil, "D 2.0": nil,
+	}
+	flipping := "target 'App' do\n  pod 'A'\n  pod 'B'\nend\n"
+	steady := "target 'App' do\n  pod 'C', '1.0'\n  pod 'D'\nend\n"
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name         string
+		source       string
+		maxTimes     uint
+		ctx          context.Context
+		wantReason   string
+		wantTimes    uint
+		wantChanging string
+	}{
+		{name: "oscillation", source: flipping, wantReason: EvolutionErrorOscillation, wantTimes: 3, wantChanging: "A,B"},
+		{name: "max iterations", source: flipping, maxTimes: 2, wantReason: EvolutionErrorMaxIterations, wantTimes: 2, wantChanging: "A,B"},
+		{name: "steady", source: steady},
+		{name: "steady beyond max iterations", source: steady, maxTimes: 1, wantReason: EvolutionErrorMaxIterations, wantTimes: 1},
+		{name: "canceled", source: steady, ctx: canceled, wantReason: EvolutionErrorCanceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aPodfile, err := NewPodfileWithBytes("Podfile", []byte(tt.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			aMapPodfile, err := NewMapPodfile(aPodfile, "App", nil, index.queryVersion, index.queryDepends)
+			if err != nil {
+				t.Fatal(err)
+			}
+			aMapPodfile.MaxEvolutionTimes = tt.maxTimes
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			err = aMapPodfile.EvolutionWithContext(ctx, nil)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v := aMapPodfile.Map["D"].UsefulV; v != "1.0" {
+					t.Errorf("D = %s, want 1.0", v)
+				}
+				return
+			}
+			eErr, ok := err.(*EvolutionError)
+			if !ok {
+				t.Fatalf("Evolution() error = %v, want an *EvolutionError", err)
+			}
+			if eErr.Reason != tt.wantReason || eErr.Iterations != tt.wantTimes {
+				t.Errorf("Evolution() error = %s after %d iterations, want %s after %d", eErr.Reason, eErr.Iterations, tt.wantReason, tt.wantTimes)
+			}
+			if got := strings.Join(eErr.Changing, ","); got != tt.wantChanging {
+				t.Errorf("Changing = %s, want %s", got, tt.wantChanging)
+			}
+			if tt.wantReason == EvolutionErrorCanceled && eErr.Err != context.Canceled {
+				t.Errorf("Err = %v, want context.Canceled", eErr.Err)
+			}
+		})
+	}
+}
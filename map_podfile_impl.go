@@ -1,39 +1,99 @@
 package pod
 
 import (
-	"strings"
-
+	"bytes"
+	"context"
 	"errors"
-
+	"sort"
 	"strconv"
-
-	"bytes"
+	"strings"
 
 	fdt "github.com/go-hayden-base/foundation"
 	ver "github.com/go-hayden-base/version"
 )
 
 func NewMapPodfile(aPodfile *Podfile, target string, updateRule map[string]string, qvFunc QueryVersionFunc, qdFunc QueryDependsFunc) (*MapPodfile, error) {
+	return NewMapPodfileWithTargets(aPodfile, []string{target}, updateRule, qvFunc, qdFunc)
+}
+
+// NewMapPodfileWithTargets resolves the modules of several targets together,
+// so a module shared by them gets one version. targets nil means every
+// concrete target of the Podfile, a name the Podfile does not define is
+// skipped. Every requirement of a module, in one target or more, is kept as
+// a constraint, a local module wins over a remote one.
+func NewMapPodfileWithTargets(aPodfile *Podfile, targets []string, updateRule map[string]string, qvFunc QueryVersionFunc, qdFunc QueryDependsFunc) (*MapPodfile, error) {
+	return NewMapPodfileWithPolicy(aPodfile, targets, &UpdatePolicy{Force: updateRule}, qvFunc, qdFunc)
+}
+
+// NewMapPodfileWithPolicy is NewMapPodfileWithTargets with an UpdatePolicy
+// deciding which modules move and how far, nil means UpdateModeConservative.
+func NewMapPodfileWithPolicy(aPodfile *Podfile, targets []string, policy *UpdatePolicy, qvFunc QueryVersionFunc, qdFunc QueryDependsFunc) (*MapPodfile, error) {
 	if aPodfile == nil {
 		return nil, errors.New("Argement aPodfile is nil")
 	}
 	aMapPodfile := new(MapPodfile)
 	aMapPodfile.Map = make(map[string]*MapPodfileModule)
 	aMapPodfile.sameParentMap = make(map[string]map[string]*MapPodfileModule)
+	aMapPodfile.provenance = make(map[string]*MapPodfileProvenance)
+	aMapPodfile.targetModules = make(map[string][]string)
 
-	aMapPodfile.updateRule = updateRule
-	aMapPodfile.queryVersionFunc = qvFunc
-	aMapPodfile.queryDependsFunc = qdFunc
+	if policy == nil {
+		policy = new(UpdatePolicy)
+	}
+	aMapPodfile.policy = policy
+	aMapPodfile.updateRule = make(map[string]string, len(policy.Force))
+	for module, version := range policy.Force {
+		aMapPodfile.updateRule[module] = version
+	}
+	aMapPodfile.queryVersionFunc = aMapPodfile.memoQueryVersionFunc(qvFunc)
+	aMapPodfile.queryDependsFunc = aMapPodfile.memoQueryDependsFunc(qdFunc)
 
-	aTarget := aPodfile.TargetWithName(target)
-	if aTarget != nil {
-		for _, aModule := range aTarget.Modules {
-			aMapModule := NewMapPodfileModule(aModule)
-			aMapPodfile.Map[aMapModule.Name] = aMapModule
+	if targets == nil {
+		for _, aTarget := range aPodfile.Targets {
+			if !aTarget.Abstract && !aTarget.IsRoot() {
+				targets = append(targets, aTarget.Name)
+			}
 		}
 	}
+	for _, target := range targets {
+		aTarget := aPodfile.TargetWithName(target)
+		if aTarget == nil || aMapPodfile.targetModules[target] != nil {
+			continue
+		}
+		source := "Podfile"
+		if len(targets) > 1 {
+			source += "(" + target + ")"
+		}
+		names := make([]string, 0, len(aTarget.Modules))
+		for _, aModule := range aTarget.EffectiveModules() {
+			names = append(names, aModule.N)
+			aMapModule, ok := aMapPodfile.Map[aModule.N]
+			isNew := !ok || (aModule.IsLocal() && !aMapModule.IsLocal())
+			if isNew {
+				aMapModule = NewMapPodfileModule(aModule)
+				aMapPodfile.Map[aMapModule.Name] = aMapModule
+			}
+			if aModule.IsLocal() {
+				continue
+			}
+			for idx, r := range aModule.VersionRequirements() {
+				aMapPodfile.recordConstraint(aMapModule.Name, r, source, "")
+				switch {
+				case aMapModule.IsLocal() || (isNew && idx == 0):
+					// OriginV of a new module is its first requirement
+				case aMapModule.OriginV == TagEmptyVersion:
+					aMapModule.OriginV = r
+				default:
+					aMapModule.addConstraint(r)
+				}
+			}
+		}
+		aMapPodfile.targetModules[target] = names
+		aMapPodfile.targets = append(aMapPodfile.targets, target)
+	}
 	aMapPodfile.versionifyRuleIfNeeds()
 	aMapPodfile.convertConstraintIfNeeds()
+	aMapPodfile.updateTargets()
 	return aMapPodfile, nil
 }
 
@@ -57,29 +117,99 @@ func NewMapPodfileModule(aModule *PodfileModule) *MapPodfileModule {
 
 // Evolution podfile
 func (s *MapPodfile) Evolution(logFunc func(msg string)) error {
-	s.evolutionTimes++
-	if logFunc != nil {
-		logFunc("执行依赖分析[ 第" + strconv.FormatUint(uint64(s.evolutionTimes), 10) + "次迭代 ] ...")
-	}
-	s.fillNewestVersion()
-	s.buildSameParentMap()
-	if err := s.singleModuleEvolution(); err != nil {
-		return err
+	return s.EvolutionWithContext(context.Background(), logFunc)
+}
+
+// EvolutionWithContext iterates until every dependency is satisfied. It
+// returns an *EvolutionError when ctx is done, when MaxEvolutionTimes is
+// reached or when an assignment of versions comes back (oscillation).
+func (s *MapPodfile) EvolutionWithContext(ctx context.Context, logFunc func(msg string)) error {
+	maxTimes := s.MaxEvolutionTimes
+	if maxTimes == 0 {
+		maxTimes = DefaultMaxEvolutionTimes
 	}
-	if err := s.clusterModuleEvolution(); err != nil {
-		return err
+	defer func() {
+		s.evolutionTimes = 0
+	}()
+	snapshots := make([]map[string]string, 0, 5)
+	seen := make(map[string]int)
+	for {
+		if err := ctx.Err(); err != nil {
+			return &EvolutionError{Reason: EvolutionErrorCanceled, Iterations: s.evolutionTimes, Changing: changingModules(snapshots), Err: err}
+		}
+		if s.evolutionTimes >= maxTimes {
+			return &EvolutionError{Reason: EvolutionErrorMaxIterations, Iterations: s.evolutionTimes, Changing: changingModules(snapshots)}
+		}
+		s.evolutionTimes++
+		if logFunc != nil {
+			logFunc("执行依赖分析[ 第" + strconv.FormatUint(uint64(s.evolutionTimes), 10) + "次迭代 ] ...")
+		}
+		s.fillNewestVersion()
+		s.buildSameParentMap()
+		if err := s.singleModuleEvolution(); err != nil {
+			return err
+		}
+		if err := s.clusterModuleEvolution(); err != nil {
+			return err
+		}
+		if err := s.fillDepends(); err != nil {
+			return err
+		}
+
+		if s.check() {
+			s.reduce()
+			s.genBeDepended()
+			s.updateTargets()
+			return nil
+		}
+
+		snapshot, key := s.snapshot()
+		if idx, ok := seen[key]; ok {
+			changing := changingModules(append(snapshots[idx:], snapshot))
+			if len(changing) == 0 {
+				changing = s.unsatisfiedModules()
+			}
+			return &EvolutionError{Reason: EvolutionErrorOscillation, Iterations: s.evolutionTimes, Changing: changing}
+		}
+		seen[key] = len(snapshots)
+		snapshots = append(snapshots, snapshot)
 	}
-	if err := s.fillDepends(); err != nil {
-		return err
+}
+
+// unsatisfiedModules returns the modules whose version does not match a
+// constraint of the modules depending on them.
+func (s *MapPodfile) unsatisfiedModules() []string {
+	res := make([]string, 0, 2)
+	for _, aModule := range s.Map {
+		depends, _ := aModule.Depends()
+		for _, aDepend := range depends {
+			aExistModule, ok := s.Map[aDepend.N]
+			if !ok || aDepend.V == TagEmptyVersion || aExistModule.UsefulV == TagEmptyVersion || aExistModule.UsefulV == TagUnknownVersion {
+				continue
+			}
+			if !ver.MatchVersionConstraint(aDepend.V, aExistModule.UsefulV) && !fdt.SliceContainsStr(aDepend.N, res) {
+				res = append(res, aDepend.N)
+			}
+		}
 	}
+	sort.Strings(res)
+	return res
+}
 
-	if s.check() {
-		s.evolutionTimes = 0
-		s.reduce()
-		s.genBeDepended()
-		return nil
+// snapshot returns the current assignment of versions and a key for it.
+func (s *MapPodfile) snapshot() (map[string]string, string) {
+	res := make(map[string]string, len(s.Map))
+	names := make([]string, 0, len(s.Map))
+	for name, aModule := range s.Map {
+		res[name] = aModule.UsefulV
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buffer bytes.Buffer
+	for _, name := range names {
+		buffer.WriteString(name + "@" + res[name] + ";")
 	}
-	return s.Evolution(logFunc)
+	return res, buffer.String()
 }
 
 func (s *MapPodfile) buildSameParentMap() {
@@ -117,20 +247,19 @@ func (s *MapPodfile) buildSameParentMap() {
 
 func (s *MapPodfile) singleModuleEvolution() error {
 	canQueryVersion := s.queryVersionFunc != nil
-	for _, aModule := range s.Map {
-		if strings.Index(aModule.Name, "/") > -1 {
-			continue
-		}
-		if _, ok := s.sameParentMap[aModule.Name]; ok {
-			continue
-		}
-
-		needsQuery := aModule.UsefulV == TagUnknownVersion
-		if !needsQuery {
-			needsQuery = needsQuery || !ver.MatchVersionConstrains(aModule.constraints, aModule.UsefulV)
+	if canQueryVersion {
+		jobs := make([]func(), 0, len(s.Map))
+		for _, aModule := range s.Map {
+			if s.singleNeedsQuery(aModule) {
+				name, constraints := aModule.Name, aModule.constraints
+				jobs = append(jobs, func() { s.queryVersion(name, constraints) })
+			}
 		}
-		if canQueryVersion && needsQuery {
-			v, err := s.queryVersionFunc(aModule.Name, aModule.constraints)
+		s.prefetch(jobs)
+	}
+	for _, aModule := range s.Map {
+		if canQueryVersion && s.singleNeedsQuery(aModule) {
+			v, err := s.queryVersion(aModule.Name, aModule.constraints)
 			if err != nil {
 				return err
 			}
@@ -140,69 +269,109 @@ func (s *MapPodfile) singleModuleEvolution() error {
 			} else {
 				aModule.UsefulV = v
 			}
+			s.recordPick(aModule.Name, aModule.UsefulV, PickByQuery, aModule.constraints)
+		}
+		if !s.isClusterModule(aModule) {
+			aModule.constraints = nil
 		}
-		aModule.constraints = nil
 	}
 	return nil
 }
 
+func (s *MapPodfile) isClusterModule(aModule *MapPodfileModule) bool {
+	if strings.Index(aModule.Name, "/") > -1 {
+		return true
+	}
+	_, ok := s.sameParentMap[aModule.Name]
+	return ok
+}
+
+func (s *MapPodfile) singleNeedsQuery(aModule *MapPodfileModule) bool {
+	if s.isClusterModule(aModule) {
+		return false
+	}
+	return aModule.UsefulV == TagUnknownVersion || !ver.MatchVersionConstrains(aModule.constraints, aModule.UsefulV)
+}
+
+// fillNewestVersion queries the newest version the policy allows (NewestV)
+// and the newest version regardless of the Podfile and the policy (LatestV).
 func (s *MapPodfile) fillNewestVersion() {
 	canQueryVersion := s.queryVersionFunc != nil
+	if canQueryVersion {
+		jobs := make([]func(), 0, 2*len(s.Map))
+		for _, aModule := range s.Map {
+			if aModule.NewestV == TagEmptyVersion {
+				name := aModule.Name
+				constraints := s.policy.constraints(name, s.baseVersion(name))
+				latestConstraints := s.latestConstraints(name)
+				jobs = append(jobs, func() { s.queryVersionFunc(name, constraints) }, func() { s.queryVersionFunc(name, latestConstraints) })
+			}
+		}
+		s.prefetch(jobs)
+	}
 	for _, aModule := range s.Map {
 		if aModule.NewestV != TagEmptyVersion {
 			continue
 		}
 		if !canQueryVersion {
 			aModule.NewestV = TagUnknownVersion
+			aModule.LatestV = TagUnknownVersion
 			continue
 		}
-		v, err := s.queryVersionFunc(aModule.Name, nil)
-		if err != nil {
-			aModule.NewestV = TagUnknownVersion
-			continue
-		}
-		aModule.NewestV = v
+		aModule.NewestV = s.queryNewestVersion(aModule.Name, s.policy.constraints(aModule.Name, s.baseVersion(aModule.Name)))
+		aModule.LatestV = s.queryNewestVersion(aModule.Name, s.latestConstraints(aModule.Name))
 	}
 }
 
+func (s *MapPodfile) queryNewestVersion(module string, constraints []string) string {
+	v, err := s.queryVersionFunc(module, constraints)
+	if err != nil || v == TagEmptyVersion {
+		return TagUnknownVersion
+	}
+	return v
+}
+
+// latestConstraints only keeps PrereleaseConstraint when the policy allows
+// prereleases of module.
+func (s *MapPodfile) latestConstraints(module string) []string {
+	if s.policy.allowsPrerelease(module) {
+		return []string{PrereleaseConstraint}
+	}
+	return nil
+}
+
 func (s *MapPodfile) clusterModuleEvolution() error {
 	canQueryVersion := s.queryVersionFunc != nil
-	for parent, mm := range s.sameParentMap {
-		constraints, versions := make([]string, 0, 5), make([]string, 0, 2)
-		for _, aModule := range mm {
-			if len(aModule.constraints) > 0 {
-				constraints = append(constraints, aModule.constraints...)
-			}
-			if aModule.UsefulV != TagEmptyVersion && aModule.UsefulV != TagUnknownVersion {
-				versions = append(versions, aModule.UsefulV)
+	if canQueryVersion {
+		jobs := make([]func(), 0, len(s.sameParentMap))
+		for parent, mm := range s.sameParentMap {
+			if constraints, _, needsQuery := clusterConstraints(mm); needsQuery {
+				name := parent
+				jobs = append(jobs, func() { s.queryVersion(name, constraints) })
 			}
 		}
-		useful := TagUnknownVersion
-		if len(versions) == 0 {
-			if canQueryVersion {
-				v, err := s.queryVersionFunc(parent, constraints)
-				if err != nil {
-					return err
-				}
-				useful = v
+		s.prefetch(jobs)
+	}
+	for parent, mm := range s.sameParentMap {
+		constraints, vs, needsQuery := clusterConstraints(mm)
+		useful, pickBy := TagUnknownVersion, PickByQuery
+		if !needsQuery {
+			v, err := ver.MaxVersion("", vs...)
+			if err != nil {
+				return err
 			}
-		} else {
-			vs := ver.MatchConstraintsVersions(constraints, versions)
-			if len(vs) > 0 {
-				v, err := ver.MaxVersion("", vs...)
-				if err != nil {
-					return err
-				}
-				useful = v
-			} else if canQueryVersion {
-				v, err := s.queryVersionFunc(parent, constraints)
-				if err != nil {
-					return err
-				}
-				useful = v
+			useful, pickBy = v, PickByCluster
+		} else if canQueryVersion {
+			v, err := s.queryVersion(parent, constraints)
+			if err != nil {
+				return err
 			}
+			useful = v
 		}
 		for _, aModule := range mm {
+			if aModule.UsefulV != useful {
+				s.recordPick(aModule.Name, useful, pickBy, constraints)
+			}
 			aModule.UsefulV = useful
 			aModule.constraints = nil
 		}
@@ -214,6 +383,14 @@ func (s *MapPodfile) fillDepends() error {
 	if s.queryDependsFunc == nil {
 		return nil
 	}
+	jobs := make([]func(), 0, len(s.Map))
+	for _, aModule := range s.Map {
+		if _, ok := aModule.Depends(); !ok && aModule.UsefulV != TagEmptyVersion && aModule.UsefulV != TagUnknownVersion {
+			name, version := aModule.Name, aModule.UsefulV
+			jobs = append(jobs, func() { s.queryDependsFunc(name, version) })
+		}
+	}
+	s.prefetch(jobs)
 	for _, aModule := range s.Map {
 		if aModule.UsefulV == TagEmptyVersion {
 			return errors.New(aModule.Name + " has an empty useful version (with origin " + aModule.OriginV + " )")
@@ -254,15 +431,21 @@ func (s *MapPodfile) check() bool {
 					done = false
 				}
 				aExistModule.addConstraint(aDepend.V)
+				s.recordConstraint(dependName, aDepend.V, aModule.Name, aModule.UsefulV)
 			} else {
 				done = false
-				aModule := new(MapPodfileModule)
-				aModule.Name = aDepend.N
-				aModule.OriginV = TagUnknownVersion
-				aModule.UsefulV, _ = s.searchVersionFromRule(aModule.Name)
-				aModule.addConstraint(aDepend.V)
-				aModule.AddState(StateMapPodfileModuleNew | StateMapPodfileModuleImplicit)
-				s.Map[aModule.Name] = aModule
+				aNewModule := new(MapPodfileModule)
+				aNewModule.Name = aDepend.N
+				aNewModule.OriginV = TagUnknownVersion
+				version, ok := s.searchVersionFromRule(aNewModule.Name)
+				aNewModule.UsefulV = version
+				if ok {
+					s.recordPick(aNewModule.Name, version, PickByRule, nil)
+				}
+				aNewModule.addConstraint(aDepend.V)
+				s.recordConstraint(aNewModule.Name, aDepend.V, aModule.Name, aModule.UsefulV)
+				aNewModule.AddState(StateMapPodfileModuleNew | StateMapPodfileModuleImplicit)
+				s.Map[aNewModule.Name] = aNewModule
 			}
 		}
 	}
@@ -317,6 +500,69 @@ func (s *MapPodfile) genBeDepended() {
 	}
 }
 
+// *** MapPodfile - Targets ***
+
+// TargetNames returns the resolved targets in the order they were given.
+func (s *MapPodfile) TargetNames() []string {
+	return s.targets
+}
+
+// TargetModules returns the modules a target links: its own modules and
+// everything they depend on, sorted by name. Run Evolution first to have
+// the implicit modules.
+func (s *MapPodfile) TargetModules(target string) []*MapPodfileModule {
+	names := s.targetClosure(target)
+	res := make([]*MapPodfileModule, 0, len(names))
+	for _, name := range names {
+		res = append(res, s.Map[name])
+	}
+	return res
+}
+
+func (s *MapPodfile) targetClosure(target string) []string {
+	visited := make(map[string]bool)
+	queue := append([]string(nil), s.targetModules[target]...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		aModule, ok := s.Map[name]
+		if !ok || visited[name] {
+			continue
+		}
+		visited[name] = true
+		depends, _ := aModule.Depends()
+		for _, aDepend := range depends {
+			queue = append(queue, aDepend.N)
+		}
+	}
+	res := make([]string, 0, len(visited))
+	for name := range visited {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// updateTargets fills the targets of every module and flags the ones linked
+// by more than one target as common.
+func (s *MapPodfile) updateTargets() {
+	for _, aModule := range s.Map {
+		aModule.Targets = nil
+	}
+	for _, target := range s.targets {
+		for _, name := range s.targetClosure(target) {
+			s.Map[name].Targets = append(s.Map[name].Targets, target)
+		}
+	}
+	for _, aModule := range s.Map {
+		if len(aModule.Targets) > 1 {
+			aModule.AddState(StateMapPodfileModuleCommon)
+		} else {
+			aModule.RemoveState(StateMapPodfileModuleCommon)
+		}
+	}
+}
+
 // *** MapPodfile - Exec after new ***
 func (s *MapPodfile) versionifyRuleIfNeeds() {
 	if s.updateRule == nil {
@@ -329,12 +575,33 @@ func (s *MapPodfile) versionifyRuleIfNeeds() {
 
 func (s *MapPodfile) convertConstraintIfNeeds() {
 	for _, aModule := range s.Map {
+		pickBy := PickByPodfile
+		if aModule.IsLocal() {
+			pickBy = PickByLocal
+		} else if !ver.IsVersion(aModule.OriginV) {
+			pickBy = PickByQuery
+		}
+		requirement := aModule.OriginV
 		aModule.OriginV = s.versionify(aModule.Name, aModule.OriginV)
 		if version, ok := s.searchVersionFromRule(aModule.Name); ok {
 			aModule.UsefulV = version
+			s.recordPick(aModule.Name, version, PickByRule, nil)
 			continue
 		}
+		if !aModule.IsLocal() && s.queryVersionFunc != nil && s.policy.canUpdate(aModule.Name) {
+			var constraints []string
+			if requirement != TagEmptyVersion && !ver.IsVersion(requirement) {
+				constraints = []string{requirement}
+			}
+			constraints = append(constraints, aModule.constraints...)
+			if v, err := s.queryVersion(aModule.Name, constraints); err == nil && v != TagEmptyVersion {
+				aModule.UsefulV = v
+				s.recordPick(aModule.Name, v, PickByPolicy, constraints)
+				continue
+			}
+		}
 		aModule.UsefulV = aModule.OriginV
+		s.recordPick(aModule.Name, aModule.UsefulV, pickBy, nil)
 	}
 }
 
@@ -368,6 +635,21 @@ func (s *MapPodfile) versionify(module, version string) string {
 	return TagUnknownVersion
 }
 
+// ** EvolutionError Impl **
+func (s *EvolutionError) Error() string {
+	msg := "依赖分析未收敛[ " + s.Reason + ", 第" + strconv.FormatUint(uint64(s.Iterations), 10) + "次迭代 ]"
+	if s.Module != "" {
+		msg += " " + s.Module + " 没有满足 [" + strings.Join(s.Constraints, ", ") + "] 的版本, 更新策略: " + s.Policy
+	}
+	if len(s.Changing) > 0 {
+		msg += " 仍在变化的模块: " + strings.Join(s.Changing, ", ")
+	}
+	if s.Err != nil {
+		msg += " " + s.Err.Error()
+	}
+	return msg
+}
+
 // ** MapPodfileModule Impl **
 func (s *MapPodfileModule) UpgradeTag() string {
 	c := ver.CompareVersion(s.OriginV, s.UsefulV)
@@ -471,3 +753,48 @@ func (s *MapPodfileModule) DependsString() string {
 	}
 	return buffer.String()
 }
+
+// ** Func Private **
+
+// clusterConstraints merges the constraints of the subspecs of a pod. When
+// some of their versions match them all the highest is kept, otherwise the
+// pod has to be queried.
+func clusterConstraints(mm map[string]*MapPodfileModule) ([]string, []string, bool) {
+	constraints, versions := make([]string, 0, 5), make([]string, 0, 2)
+	for _, aModule := range mm {
+		if len(aModule.constraints) > 0 {
+			constraints = append(constraints, aModule.constraints...)
+		}
+		if aModule.UsefulV != TagEmptyVersion && aModule.UsefulV != TagUnknownVersion {
+			versions = append(versions, aModule.UsefulV)
+		}
+	}
+	if len(versions) == 0 {
+		return constraints, nil, true
+	}
+	vs := ver.MatchConstraintsVersions(constraints, versions)
+	return constraints, vs, len(vs) == 0
+}
+
+// changingModules returns the modules whose version differs between the
+// snapshots, added modules included.
+func changingModules(snapshots []map[string]string) []string {
+	if len(snapshots) < 2 {
+		return nil
+	}
+	changing := make(map[string]bool)
+	for i := 1; i < len(snapshots); i++ {
+		prev, cur := snapshots[i-1], snapshots[i]
+		for name, v := range cur {
+			if pv, ok := prev[name]; !ok || pv != v {
+				changing[name] = true
+			}
+		}
+	}
+	res := make([]string, 0, len(changing))
+	for name := range changing {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,162 @@
+package pod
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	fdt "github.com/go-hayden-base/foundation"
+	ver "github.com/go-hayden-base/version"
+)
+
+// ** MapPodfile Outdated Impl **
+
+// Outdated builds the report of the modules of s, run Evolution first.
+func (s *MapPodfile) Outdated() *OutdatedReport {
+	aReport := &OutdatedReport{Modules: make([]*OutdatedModule, 0, len(s.Map))}
+	for _, aModule := range s.Map {
+		if aModule.IsLocal() {
+			continue
+		}
+		aOutdated := &OutdatedModule{
+			Name:       aModule.Name,
+			Current:    aModule.OriginV,
+			Resolvable: aModule.UsefulV,
+			Latest:     aModule.LatestV,
+		}
+		from := aModule.OriginV
+		if !ver.IsVersion(from) {
+			from = aModule.UsefulV
+		}
+		aOutdated.Gap = versionGap(from, aModule.LatestV)
+		aOutdated.ResolvableGap = versionGap(aModule.UsefulV, aModule.LatestV)
+		aOutdated.Outdated = aOutdated.Gap != OutdatedGapNone && aOutdated.Gap != OutdatedGapUnknown
+		if aOutdated.ResolvableGap != OutdatedGapNone && ver.IsVersion(aModule.LatestV) {
+			aOutdated.Blockers = s.blockersOf(aModule.Name, aModule.LatestV)
+		}
+		aReport.Modules = append(aReport.Modules, aOutdated)
+	}
+	sort.Slice(aReport.Modules, func(i, j int) bool {
+		return aReport.Modules[i].Name < aReport.Modules[j].Name
+	})
+	return aReport
+}
+
+// blockersOf returns the constraints on module, or on another subspec of
+// its pod, and the caps of the policy that version does not match.
+func (s *MapPodfile) blockersOf(module, version string) []*OutdatedBlocker {
+	baseName := fdt.StrSplitFirst(module, "/")
+	res := make([]*OutdatedBlocker, 0, 1)
+	add := func(aBlocker *OutdatedBlocker) {
+		for _, exist := range res {
+			if *exist == *aBlocker {
+				return
+			}
+		}
+		res = append(res, aBlocker)
+	}
+	for _, aOtherModule := range s.Map {
+		if fdt.StrSplitFirst(aOtherModule.Name, "/") == baseName {
+			continue
+		}
+		depends, _ := aOtherModule.Depends()
+		for _, aDepend := range depends {
+			if fdt.StrSplitFirst(aDepend.N, "/") != baseName || aDepend.V == TagEmptyVersion {
+				continue
+			}
+			if !ver.MatchVersionConstraint(aDepend.V, version) {
+				add(&OutdatedBlocker{Module: aOtherModule.Name, Version: aOtherModule.UsefulV, Constraint: aDepend.V})
+			}
+		}
+	}
+	if p := s.Provenance(module); p != nil {
+		for _, c := range p.Constraints {
+			if strings.HasPrefix(c.Source, "Podfile") && !ver.MatchVersionConstraint(c.Constraint, version) {
+				add(&OutdatedBlocker{Module: c.Source, Constraint: c.Constraint})
+			}
+		}
+	}
+	for _, c := range s.policy.constraints(module, s.baseVersion(module)) {
+		if !ver.MatchVersionConstraint(c, version) {
+			add(&OutdatedBlocker{Module: OutdatedBlockerPolicy, Constraint: c})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Module < res[j].Module
+	})
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// ** OutdatedReport Impl **
+func (s *OutdatedReport) JSON() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// Table renders the report as aligned columns, outdated modules only when
+// onlyOutdated is true.
+func (s *OutdatedReport) Table(onlyOutdated bool) string {
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+	w.Write([]byte("Pod\tCurrent\tResolvable\tLatest\tGap\tBlocked by\n"))
+	for _, aModule := range s.Modules {
+		if onlyOutdated && !aModule.Outdated {
+			continue
+		}
+		blockers := make([]string, 0, len(aModule.Blockers))
+		for _, aBlocker := range aModule.Blockers {
+			blockers = append(blockers, aBlocker.String())
+		}
+		blockedBy := strings.Join(blockers, ", ")
+		if blockedBy == "" {
+			blockedBy = "-"
+		}
+		w.Write([]byte(strings.Join([]string{aModule.Name, aModule.Current, aModule.Resolvable, aModule.Latest, aModule.Gap, blockedBy}, "\t") + "\n"))
+	}
+	w.Flush()
+	return buffer.String()
+}
+
+// ** OutdatedBlocker Impl **
+func (s *OutdatedBlocker) String() string {
+	res := s.Module
+	if s.Version != TagEmptyVersion {
+		res += " " + s.Version
+	}
+	return res + " (" + s.Constraint + ")"
+}
+
+// ** Func Private **
+
+// versionGap classifies the move from a to b by the first segment that
+// differs, a downgrade or an equal version is OutdatedGapNone.
+func versionGap(a, b string) string {
+	if !ver.IsVersion(a) || !ver.IsVersion(b) {
+		return OutdatedGapUnknown
+	}
+	if ver.CompareVersion(a, b) >= 0 {
+		return OutdatedGapNone
+	}
+	pa, pb := versionSegments(a), versionSegments(b)
+	if pa[0] != pb[0] {
+		return OutdatedGapMajor
+	}
+	if pa[1] != pb[1] {
+		return OutdatedGapMinor
+	}
+	return OutdatedGapPatch
+}
+
+func versionSegments(v string) [2]int {
+	var res [2]int
+	parts := strings.Split(strings.SplitN(v, "-", 2)[0], ".")
+	for idx := 0; idx < len(parts) && idx < 2; idx++ {
+		res[idx], _ = strconv.Atoi(parts[idx])
+	}
+	return res
+}
@@ -0,0 +1,151 @@
+package pod
+
+import (
+	"testing"
+)
+
+func newTestOutdatedReport(t *testing.T) *OutdatedReport {
+	t.Helper()
+	index := testIndex{
+		"A 1.0": nil, "A 1.1": nil, "A 2.0": nil,
+		"B 1.0": {"C < 1.5"}, "B 1.2": {"C < 1.5"},
+		"C 1.0": nil, "C 1.4.1": nil, "C 1.5": nil,
+	}
+	source := "target 'App' do\n  pod 'A', '1.0'\n  pod 'B', '~> 1.0'\n  pod 'L', :path => '../L'\nend\n"
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aMapPodfile, err := NewMapPodfile(aPodfile, "App", nil, index.queryVersion, index.queryDepends)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aMapPodfile.Evolution(nil); err != nil {
+		t.Fatal(err)
+	}
+	return aMapPodfile.Outdated()
+}
+
+func TestMapPodfileOutdated(t *testing.T) {
+	aReport := newTestOutdatedReport(t)
+	tests := []struct {
+		name         string
+		onlyOutdated bool
+		want         string
+	}{
+		{
+			name: "all",
+			want: `Pod  Current  Resolvable  Latest  Gap    Blocked by
+A    1.0      1.0         2.0     major  Podfile (1.0)
+B    1.2      1.2         1.2     none   -
+C    *        1.4.1       1.5     minor  B 1.2 (< 1.5)
+`,
+		},
+		{
+			name:         "only outdated",
+			onlyOutdated: true,
+			want: `Pod  Current  Resolvable  Latest  Gap    Blocked by
+A    1.0      1.0         2.0     major  Podfile (1.0)
+C    *        1.4.1       1.5     minor  B 1.2 (< 1.5)
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := aReport.Table(tt.onlyOutdated); got != tt.want {
+				t.Errorf("Table(%v) =\n%s\nwant\n%s", tt.onlyOutdated, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutdatedReportJSON(t *testing.T) {
+	b, err := newTestOutdatedReport(t).JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"modules":[` +
+		`{"name":"A","current":"1.0","resolvable":"1.0","latest":"2.0","gap":"major","resolvable_gap":"major","outdated":true,` +
+		`"blockers":[{"module":"Podfile","constraint":"1.0"}]},` +
+		`{"name":"B","current":"1.2","resolvable":"1.2","latest":"1.2","gap":"none","resolvable_gap":"none","outdated":false},` +
+		`{"name":"C","current":"*","resolvable":"1.4.1","latest":"1.5","gap":"minor","resolvable_gap":"minor","outdated":true,` +
+		`"blockers":[{"module":"B","version":"1.2","constraint":"\u003c 1.5"}]}]}`
+	if got := string(b); got != want {
+		t.Errorf("JSON() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMapPodfileOutdatedIgnoresCaps(t *testing.T) {
+	index := testIndex{
+		"A 1.0": nil, "A 1.1": nil, "A 2.0": nil,
+		"B 1.0": nil, "B 1.2": nil, "B 2.0": nil,
+	}
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte("target 'App' do\n  pod 'A', '1.0'\n  pod 'B', '~> 1.0'\nend\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := &UpdatePolicy{Mode: UpdateModeAll, DefaultCap: UpdateCapPatch}
+	aMapPodfile, err := NewMapPodfileWithPolicy(aPodfile, []string{"App"}, policy, index.queryVersion, index.queryDepends)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aMapPodfile.Evolution(nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name          string
+		current       string
+		resolvable    string
+		latest        string
+		newest        string
+		gap           string
+		resolvableGap string
+		blockers      []OutdatedBlocker
+	}{
+		{
+			name: "A", current: "1.0", resolvable: "1.0", latest: "2.0", newest: "1.0",
+			gap: OutdatedGapMajor, resolvableGap: OutdatedGapMajor,
+			blockers: []OutdatedBlocker{
+				{Module: "Podfile", Constraint: "1.0"},
+				{Module: OutdatedBlockerPolicy, Constraint: "< 1.1"},
+			},
+		},
+		{
+			name: "B", current: "1.2", resolvable: "1.2", latest: "2.0", newest: "1.2",
+			gap: OutdatedGapMajor, resolvableGap: OutdatedGapMajor,
+			blockers: []OutdatedBlocker{
+				{Module: "Podfile", Constraint: "~> 1.0"},
+				{Module: OutdatedBlockerPolicy, Constraint: "< 1.3"},
+			},
+		},
+	}
+	modules := make(map[string]*OutdatedModule)
+	for _, aModule := range aMapPodfile.Outdated().Modules {
+		modules[aModule.Name] = aModule
+	}
+	for _, tt := range tests {
+		got := modules[tt.name]
+		if got == nil {
+			t.Errorf("%s is not in the report", tt.name)
+			continue
+		}
+		if got.Current != tt.current || got.Resolvable != tt.resolvable || got.Latest != tt.latest {
+			t.Errorf("%s: versions = %s %s %s, want %s %s %s", tt.name, got.Current, got.Resolvable, got.Latest, tt.current, tt.resolvable, tt.latest)
+		}
+		if newest := aMapPodfile.Map[tt.name].NewestV; newest != tt.newest {
+			t.Errorf("%s: NewestV = %s, want %s", tt.name, newest, tt.newest)
+		}
+		if got.Gap != tt.gap || got.ResolvableGap != tt.resolvableGap {
+			t.Errorf("%s: gaps = %s %s, want %s %s", tt.name, got.Gap, got.ResolvableGap, tt.gap, tt.resolvableGap)
+		}
+		if len(got.Blockers) != len(tt.blockers) {
+			t.Errorf("%s: blockers = %d, want %d", tt.name, len(got.Blockers), len(tt.blockers))
+			continue
+		}
+		for idx, b := range got.Blockers {
+			if *b != tt.blockers[idx] {
+				t.Errorf("%s: blocker = %+v, want %+v", tt.name, *b, tt.blockers[idx])
+			}
+		}
+	}
+}
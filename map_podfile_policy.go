@@ -0,0 +1,141 @@
+package pod
+
+import (
+	"strconv"
+	"strings"
+
+	fdt "github.com/go-hayden-base/foundation"
+	ver "github.com/go-hayden-base/version"
+)
+
+// ** MapPodfile Policy Impl **
+
+// queryVersion queries module with the constraints of the policy added. The
+// caps and Exclude are hard constraints: when a version matches constraints
+// but none matches the policy as well, it returns an *EvolutionError naming
+// module and the policy.
+func (s *MapPodfile) queryVersion(module string, constraints []string) (string, error) {
+	extra := s.policy.constraints(module, s.baseVersion(module))
+	if len(extra) == 0 {
+		return s.queryVersionFunc(module, constraints)
+	}
+	all := append(append(make([]string, 0, len(constraints)+len(extra)), constraints...), extra...)
+	v, err := s.queryVersionFunc(module, all)
+	if err != nil || v != TagEmptyVersion {
+		return v, err
+	}
+	if v, err := s.queryVersionFunc(module, constraints); err != nil || v == TagEmptyVersion {
+		return v, err
+	}
+	return TagEmptyVersion, &EvolutionError{
+		Reason:      EvolutionErrorPolicy,
+		Iterations:  s.evolutionTimes,
+		Module:      module,
+		Policy:      s.policy.ruleOf(module),
+		Constraints: append(append([]string(nil), constraints...), extra...),
+	}
+}
+
+// baseVersion is the OriginV the policy caps are relative to, a subspec
+// shares the one of its pod.
+func (s *MapPodfile) baseVersion(module string) string {
+	if aModule, ok := s.Map[module]; ok && ver.IsVersion(aModule.OriginV) {
+		return aModule.OriginV
+	}
+	baseName := fdt.StrSplitFirst(module, "/")
+	for name, aModule := range s.Map {
+		if fdt.StrSplitFirst(name, "/") == baseName && ver.IsVersion(aModule.OriginV) {
+			return aModule.OriginV
+		}
+	}
+	return TagEmptyVersion
+}
+
+// ** UpdatePolicy Impl **
+
+// canUpdate tells if module may leave its OriginV without being forced.
+func (s *UpdatePolicy) canUpdate(module string) bool {
+	if s == nil {
+		return false
+	}
+	baseName := fdt.StrSplitFirst(module, "/")
+	if fdt.SliceContainsStr(baseName, s.Exclude) {
+		return false
+	}
+	switch s.Mode {
+	case UpdateModeAll:
+		return true
+	case UpdateModeListed:
+		return fdt.SliceContainsStr(baseName, s.Listed)
+	}
+	return false
+}
+
+// constraints returns the constraints the policy puts on module when it
+// moves away from base.
+func (s *UpdatePolicy) constraints(module, base string) []string {
+	if s == nil {
+		return nil
+	}
+	baseName := fdt.StrSplitFirst(module, "/")
+	res := make([]string, 0, 2)
+	if ver.IsVersion(base) {
+		if fdt.SliceContainsStr(baseName, s.Exclude) {
+			res = append(res, "<= "+base)
+		} else if c := capConstraint(s.capOf(baseName), base); c != "" {
+			res = append(res, c)
+		}
+	}
+	if s.allowsPrerelease(module) {
+		res = append(res, PrereleaseConstraint)
+	}
+	return res
+}
+
+func (s *UpdatePolicy) allowsPrerelease(module string) bool {
+	return s != nil && (s.Prerelease || fdt.SliceContainsStr(fdt.StrSplitFirst(module, "/"), s.PrereleaseModules))
+}
+
+// ruleOf names the rule of the policy that bounds module.
+func (s *UpdatePolicy) ruleOf(module string) string {
+	baseName := fdt.StrSplitFirst(module, "/")
+	if fdt.SliceContainsStr(baseName, s.Exclude) {
+		return "Exclude"
+	}
+	if c, ok := s.Caps[baseName]; ok {
+		return "Caps[" + baseName + "] " + c
+	}
+	return "DefaultCap " + s.DefaultCap
+}
+
+func (s *UpdatePolicy) capOf(baseName string) string {
+	if c, ok := s.Caps[baseName]; ok {
+		return c
+	}
+	return s.DefaultCap
+}
+
+// ** Func Private **
+
+// capConstraint turns a cap into an upper bound, "patch" of 1.2.3 is
+// "< 1.3" and "minor" is "< 2".
+func capConstraint(aCap, base string) string {
+	if aCap != UpdateCapPatch && aCap != UpdateCapMinor {
+		return ""
+	}
+	parts := strings.Split(strings.SplitN(base, "-", 2)[0], ".")
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return ""
+	}
+	if aCap == UpdateCapMinor {
+		return "< " + strconv.Itoa(major+1)
+	}
+	minor := 0
+	if len(parts) > 1 {
+		if minor, err = strconv.Atoi(parts[1]); err != nil {
+			return ""
+		}
+	}
+	return "< " + strconv.Itoa(major) + "." + strconv.Itoa(minor+1)
+}
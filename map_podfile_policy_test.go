@@ -0,0 +1,100 @@
+package pod
+
+import (
+	"testing"
+)
+
+func TestMapPodfilePolicy(t *testing.T) {
+	files := make(map[string]string)
+	for _, v := range []string{"A 1.0", "A 1.0.1", "A 1.1", "A 2.0", "A 2.1-beta", "B 1.0", "B 1.5"} {
+		name, version := splitTestKey(v)
+		files["Specs/"+name+"/"+version+"/"+name+".podspec.json"] = `{"name": "` + name + `", "version": "` + version + `"}`
+	}
+	aPod := newTestPod(t, files)
+	source := "target 'App' do\n  pod 'A', '1.0'\n  pod 'B', '1.0'\nend\n"
+	tests := []struct {
+		name   string
+		policy *UpdatePolicy
+		wantA  string
+		wantB  string
+	}{
+		{name: "nil", wantA: "1.0", wantB: "1.0"},
+		{name: "conservative", policy: &UpdatePolicy{Mode: UpdateModeConservative}, wantA: "1.0", wantB: "1.0"},
+		{name: "all", policy: &UpdatePolicy{Mode: UpdateModeAll}, wantA: "2.0", wantB: "1.5"},
+		{name: "listed", policy: &UpdatePolicy{Mode: UpdateModeListed, Listed: []string{"A"}}, wantA: "2.0", wantB: "1.0"},
+		{name: "cap", policy: &UpdatePolicy{Mode: UpdateModeAll, Caps: map[string]string{"A": UpdateCapPatch}}, wantA: "1.0.1", wantB: "1.5"},
+		{name: "default cap", policy: &UpdatePolicy{Mode: UpdateModeAll, DefaultCap: UpdateCapMinor}, wantA: "1.1", wantB: "1.5"},
+		{name: "exclude", policy: &UpdatePolicy{Mode: UpdateModeAll, Exclude: []string{"A"}}, wantA: "1.0", wantB: "1.5"},
+		{name: "prerelease", policy: &UpdatePolicy{Mode: UpdateModeAll, PrereleaseModules: []string{"A"}}, wantA: "2.1-beta", wantB: "1.5"},
+		{name: "force", policy: &UpdatePolicy{Mode: UpdateModeAll, Force: map[string]string{"A": "1.1"}}, wantA: "1.1", wantB: "1.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aPodfile, err := NewPodfileWithBytes("Podfile", []byte(source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			aMapPodfile, err := NewMapPodfileWithPolicy(aPodfile, []string{"App"}, tt.policy, NewPodQueryVersionFunc(aPod), NewPodQueryDependsFunc(aPod))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := aMapPodfile.Evolution(nil); err != nil {
+				t.Fatal(err)
+			}
+			if v := aMapPodfile.Map["A"].UsefulV; v != tt.wantA {
+				t.Errorf("A = %s, want %s", v, tt.wantA)
+			}
+			if v := aMapPodfile.Map["B"].UsefulV; v != tt.wantB {
+				t.Errorf("B = %s, want %s", v, tt.wantB)
+			}
+		})
+	}
+}
+
+func TestMapPodfilePolicyIsHard(t *testing.T) {
+	index := testIndex{
+		"A 1.0": nil, "A 1.1": nil, "A 2.0": nil,
+		"B 1.0": {"A >= 2.0"},
+	}
+	source := "target 'App' do\n  pod 'A', '1.0'\n  pod 'B', '1.0'\nend\n"
+	tests := []struct {
+		name       string
+		policy     *UpdatePolicy
+		wantA      string
+		wantPolicy string
+	}{
+		{name: "no cap", policy: &UpdatePolicy{Mode: UpdateModeAll}, wantA: "2.0"},
+		{name: "cap", policy: &UpdatePolicy{Mode: UpdateModeAll, Caps: map[string]string{"A": UpdateCapMinor}}, wantPolicy: "Caps[A] minor"},
+		{name: "default cap", policy: &UpdatePolicy{DefaultCap: UpdateCapPatch}, wantPolicy: "DefaultCap patch"},
+		{name: "exclude", policy: &UpdatePolicy{Mode: UpdateModeAll, Exclude: []string{"A"}}, wantPolicy: "Exclude"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aPodfile, err := NewPodfileWithBytes("Podfile", []byte(source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			aMapPodfile, err := NewMapPodfileWithPolicy(aPodfile, []string{"App"}, tt.policy, index.queryVersion, index.queryDepends)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = aMapPodfile.Evolution(nil)
+			if tt.wantPolicy == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v := aMapPodfile.Map["A"].UsefulV; v != tt.wantA {
+					t.Errorf("A = %s, want %s", v, tt.wantA)
+				}
+				return
+			}
+			eErr, ok := err.(*EvolutionError)
+			if !ok {
+				t.Fatalf("Evolution() error = %v, want an *EvolutionError", err)
+			}
+			if eErr.Reason != EvolutionErrorPolicy || eErr.Module != "A" || eErr.Policy != tt.wantPolicy {
+				t.Errorf("Evolution() error = %+v, want a policy error on A by %s", eErr, tt.wantPolicy)
+			}
+		})
+	}
+}
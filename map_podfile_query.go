@@ -0,0 +1,77 @@
+package pod
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ** MapPodfile Query Impl **
+
+// memoQueryVersionFunc memoizes qvFunc by module and constraints, so the
+// iterations of Evolution never query the same thing twice.
+func (s *MapPodfile) memoQueryVersionFunc(qvFunc QueryVersionFunc) QueryVersionFunc {
+	if qvFunc == nil {
+		return nil
+	}
+	return func(module string, constraints []string) (string, error) {
+		key := module + "|" + strconv.Itoa(len(constraints)) + "|" + strings.Join(constraints, "\x00")
+		r := s.memoResult(&s.versionMemo, key)
+		r.once.Do(func() {
+			r.version, r.err = qvFunc(module, constraints)
+		})
+		return r.version, r.err
+	}
+}
+
+// memoQueryDependsFunc memoizes qdFunc by module and version.
+func (s *MapPodfile) memoQueryDependsFunc(qdFunc QueryDependsFunc) QueryDependsFunc {
+	if qdFunc == nil {
+		return nil
+	}
+	return func(module, version string) ([]*DependBase, error) {
+		r := s.memoResult(&s.dependsMemo, module+"@"+version)
+		r.once.Do(func() {
+			r.depends, r.err = qdFunc(module, version)
+		})
+		return r.depends, r.err
+	}
+}
+
+func (s *MapPodfile) memoResult(memo *map[string]*p_query_result, key string) *p_query_result {
+	s.memoMutex.Lock()
+	defer s.memoMutex.Unlock()
+	if *memo == nil {
+		*memo = make(map[string]*p_query_result)
+	}
+	r, ok := (*memo)[key]
+	if !ok {
+		r = new(p_query_result)
+		(*memo)[key] = r
+	}
+	return r
+}
+
+// prefetch runs the queries of an evolution step on QueryConcurrency
+// goroutines before the step itself, which then only reads the memo. Jobs
+// must not change s. Nothing is run when QueryConcurrency is below 2, the
+// step queries one by one as it goes.
+func (s *MapPodfile) prefetch(jobs []func()) {
+	if s.QueryConcurrency < 2 || len(jobs) < 2 {
+		return
+	}
+	c := make(chan bool, s.QueryConcurrency)
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		c <- true
+		go func(f func()) {
+			defer func() {
+				<-c
+				wg.Done()
+			}()
+			f()
+		}(job)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,143 @@
+package pod
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testCountingIndex counts the calls of each query of a testIndex.
+type testCountingIndex struct {
+	testIndex
+	mutex sync.Mutex
+	calls map[string]int
+}
+
+func (s *testCountingIndex) count(key string) {
+	s.mutex.Lock()
+	s.calls[key]++
+	s.mutex.Unlock()
+}
+
+func (s *testCountingIndex) queryVersion(module string, constraints []string) (string, error) {
+	s.count(fmt.Sprintf("%s %q", module, constraints))
+	return s.testIndex.queryVersion(module, constraints)
+}
+
+func (s *testCountingIndex) queryDepends(module, version string) ([]*DependBase, error) {
+	s.count(module + "@" + version)
+	return s.testIndex.queryDepends(module, version)
+}
+
+func TestMapPodfileQueryMemo(t *testing.T) {
+	index := testIndex{
+		"A 1.0": {"C ~> 1.0", "D"}, "B 1.0": {"C >= 1.1", "D"},
+		"C 1.0": nil, "C 1.1": {"E"}, "C 1.2": {"E"},
+		"D 1.0": {"E"}, "E 1.0": nil, "E 2.0": nil,
+	}
+	source := "target 'App' do\n  pod 'A'\n  pod 'B'\nend\n"
+	want := ""
+	for _, concurrency := range []int{0, 1, 4} {
+		counting := &testCountingIndex{testIndex: index, calls: make(map[string]int)}
+		aPodfile, err := NewPodfileWithBytes("Podfile", []byte(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		aMapPodfile, err := NewMapPodfile(aPodfile, "App", nil, counting.queryVersion, counting.queryDepends)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aMapPodfile.QueryConcurrency = concurrency
+		if err := aMapPodfile.Evolution(nil); err != nil {
+			t.Fatal(err)
+		}
+		versions := make([]string, 0, len(aMapPodfile.Map))
+		for name, aModule := range aMapPodfile.Map {
+			versions = append(versions, name+"@"+aModule.UsefulV)
+		}
+		sort.Strings(versions)
+		got := strings.Join(versions, " ")
+		if want == "" {
+			want = got
+			if want != "A@1.0 B@1.0 C@1.2 D@1.0 E@2.0" {
+				t.Errorf("sequential evolution gives %s", want)
+			}
+		} else if got != want {
+			t.Errorf("QueryConcurrency %d gives %s, want %s", concurrency, got, want)
+		}
+		for key, n := range counting.calls {
+			if n > 1 {
+				t.Errorf("QueryConcurrency %d: %s queried %d times", concurrency, key, n)
+			}
+		}
+	}
+}
+
+const testEvolutionPodfile = `platform :ios, '9.0'
+
+target 'App' do
+  pod 'Foo/Core', '~> 1.0'
+  pod 'Foo/Util', '~> 1.0'
+  pod 'Bar', '>= 1.0'
+end
+`
+
+func testEvolutionRepo() map[string]string {
+	return map[string]string{
+		"Foo/1.0/Foo.podspec.json": testFooSpecWithPadding(200),
+		"Bar/1.0/Bar.podspec.json": `{"name": "Bar", "version": "1.0"}`,
+		"Bar/1.1/Bar.podspec.json": `{"name": "Bar", "version": "1.1", "dependencies": {"Qux": ["~> 1.0"]}}`,
+		"Bar/2.0/Bar.podspec.json": `{"name": "Bar", "version": "2.0"}`,
+		"Baz/2.0/Baz.podspec.json": `{"name": "Baz", "version": "2.0"}`,
+		"Baz/2.1/Baz.podspec.json": `{"name": "Baz", "version": "2.1", "dependencies": {"Qux": []}}`,
+		"Qux/1.0/Qux.podspec.json": `{"name": "Qux", "version": "1.0"}`,
+		"Qux/1.2/Qux.podspec.json": `{"name": "Qux", "version": "1.2"}`,
+	}
+}
+
+func evolveTestPodfile(t *testing.T, aPod *Pod, concurrency int) string {
+	t.Helper()
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte(testEvolutionPodfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aMapPodfile, err := NewMapPodfile(aPodfile, "App", nil, NewPodQueryVersionFunc(aPod), NewPodQueryDependsFunc(aPod))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aMapPodfile.QueryConcurrency = concurrency
+	if err := aMapPodfile.Evolution(nil); err != nil {
+		t.Fatal(err)
+	}
+	res := make([]string, 0, len(aMapPodfile.Map))
+	for name, aModule := range aMapPodfile.Map {
+		res = append(res, name+"@"+aModule.UsefulV)
+	}
+	sort.Strings(res)
+	return strings.Join(res, " ")
+}
+
+// Run with -race: the queries of a Pod are shared by the goroutines of
+// QueryConcurrency.
+func TestMapPodfileQueryConcurrency(t *testing.T) {
+	files := testEvolutionRepo()
+	want := evolveTestPodfile(t, newTestPod(t, files), 0)
+	for _, module := range []string{"Foo/Core@1.0", "Foo/Util@1.0", "Bar@1.1", "Baz@2.1", "Qux@1.2"} {
+		if !strings.Contains(want, module) {
+			t.Errorf("sequential evolution gives %s, missing %s", want, module)
+		}
+	}
+	for round := 0; round < 3; round++ {
+		// A spec already loaded, such as by an earlier resolution, is shared
+		// by the queries of its subspecs
+		aPod := newTestPod(t, files)
+		if _, err := aPod.Spec("Foo", "1.0"); err != nil {
+			t.Fatal(err)
+		}
+		if got := evolveTestPodfile(t, aPod, 4); got != want {
+			t.Errorf("QueryConcurrency 4 gives %s, want %s", got, want)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package pod
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+const testTargetsPodfile = `pod 'Root', '~> 1.0'
+
+abstract_target 'Shared' do
+  pod 'A', '~> 1.0'
+
+  target 'App' do
+    pod 'B', '~> 1.0'
+  end
+
+  target 'Widget' do
+    pod 'C', '~> 1.0'
+  end
+end
+`
+
+func TestNewMapPodfileTargets(t *testing.T) {
+	index := testIndex{"Root 1.0": nil, "A 1.0": nil, "B 1.0": nil, "C 1.0": nil}
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte(testTargetsPodfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		targets     []string
+		want        string
+		wantTargets string
+	}{
+		{name: "all", targets: nil, want: "A,B,C,Root", wantTargets: "App,Widget"},
+		{name: "one", targets: []string{"App"}, want: "A,B,Root", wantTargets: "App"},
+		{name: "abstract", targets: []string{"Shared"}, want: "A,Root", wantTargets: "Shared"},
+		{name: "unknown", targets: []string{"App", "Nope"}, want: "A,B,Root", wantTargets: "App"},
+		{name: "duplicated", targets: []string{"App", "App"}, want: "A,B,Root", wantTargets: "App"},
+		{name: "empty", targets: []string{""}, want: "", wantTargets: ""},
+		{name: "root", targets: []string{PodfileRootTargetName}, want: "Root", wantTargets: PodfileRootTargetName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aMapPodfile, err := NewMapPodfileWithTargets(aPodfile, tt.targets, nil, index.queryVersion, index.queryDepends)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, 0, len(aMapPodfile.Map))
+			for name := range aMapPodfile.Map {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if got := strings.Join(names, ","); got != tt.want {
+				t.Errorf("modules = %s, want %s", got, tt.want)
+			}
+			if got := strings.Join(aMapPodfile.TargetNames(), ","); got != tt.wantTargets {
+				t.Errorf("TargetNames() = %s, want %s", got, tt.wantTargets)
+			}
+		})
+	}
+}
+
+func TestMapPodfileTargetModules(t *testing.T) {
+	// App and Widget share A and Root, B and C both pull D in, and E is
+	// only reached from C
+	index := testIndex{
+		"Root 1.0": nil, "A 1.0": nil,
+		"B 1.0": {"D ~> 1.0"},
+		"C 1.0": {"D >= 1.0", "E"},
+		"D 1.0": nil, "E 1.0": nil,
+	}
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte(testTargetsPodfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aMapPodfile, err := NewMapPodfileWithTargets(aPodfile, nil, nil, index.queryVersion, index.queryDepends)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := aMapPodfile.Evolution(nil); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		module  string
+		targets string
+		common  bool
+	}{
+		{module: "Root", targets: "App,Widget", common: true},
+		{module: "A", targets: "App,Widget", common: true},
+		{module: "B", targets: "App"},
+		{module: "C", targets: "Widget"},
+		{module: "D", targets: "App,Widget", common: true},
+		{module: "E", targets: "Widget"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.module, func(t *testing.T) {
+			aModule := aMapPodfile.Map[tt.module]
+			if aModule == nil {
+				t.Fatalf("%s is not resolved", tt.module)
+			}
+			if got := strings.Join(aModule.Targets, ","); got != tt.targets {
+				t.Errorf("Targets = %s, want %s", got, tt.targets)
+			}
+			if aModule.IsCommon() != tt.common || (aModule.State()&StateMapPodfileModuleCommon != 0) != tt.common {
+				t.Errorf("IsCommon() = %v, want %v", aModule.IsCommon(), tt.common)
+			}
+		})
+	}
+	wantModules := map[string]string{
+		"App":    "A,B,D,Root",
+		"Widget": "A,C,D,E,Root",
+		"Nope":   "",
+	}
+	for target, want := range wantModules {
+		names := make([]string, 0, 5)
+		for _, aModule := range aMapPodfile.TargetModules(target) {
+			names = append(names, aModule.Name)
+		}
+		if got := strings.Join(names, ","); got != want {
+			t.Errorf("TargetModules(%s) = %s, want %s", target, got, want)
+		}
+	}
+}
+
+func TestMapPodfileTargetRequirements(t *testing.T) {
+	index := testIndex{
+		"A 1.0": nil, "A 1.2": nil, "A 1.4": nil, "A 1.5": nil, "A 2.0": nil,
+		"B 1.0": nil, "B 1.1": nil, "B 2.0": nil,
+	}
+	source := `target 'App' do
+  pod 'A', '>= 1.0', '< 1.5'
+  pod 'B', '~> 1.0'
+end
+
+target 'Widget' do
+  pod 'A', '!= 1.4', '< 2.0'
+end
+`
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		targets []string
+		policy  *UpdatePolicy
+		want    string
+		common  string
+		modules map[string]string
+	}{
+		{
+			name:    "both",
+			targets: nil,
+			want:    "A@1.2(App,Widget) B@1.1(App)",
+			common:  "A",
+			modules: map[string]string{"App": "A,B", "Widget": "A"},
+		},
+		{
+			name:    "both updated",
+			targets: nil,
+			policy:  &UpdatePolicy{Mode: UpdateModeAll},
+			want:    "A@1.2(App,Widget) B@1.1(App)",
+			common:  "A",
+			modules: map[string]string{"App": "A,B", "Widget": "A"},
+		},
+		{
+			name:    "App",
+			targets: []string{"App"},
+			want:    "A@1.4(App) B@1.1(App)",
+			modules: map[string]string{"App": "A,B", "Widget": ""},
+		},
+		{
+			name:    "Widget",
+			targets: []string{"Widget"},
+			want:    "A@1.5(Widget)",
+			modules: map[string]string{"App": "", "Widget": "A"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aMapPodfile, err := NewMapPodfileWithPolicy(aPodfile, tt.targets, tt.policy, index.queryVersion, index.queryDepends)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := aMapPodfile.Evolution(nil); err != nil {
+				t.Fatal(err)
+			}
+			items := make([]string, 0, len(aMapPodfile.Map))
+			common := make([]string, 0, 1)
+			for name, aModule := range aMapPodfile.Map {
+				items = append(items, name+"@"+aModule.UsefulV+"("+strings.Join(aModule.Targets, ",")+")")
+				if aModule.IsCommon() {
+					common = append(common, name)
+				}
+			}
+			sort.Strings(items)
+			sort.Strings(common)
+			if got := strings.Join(items, " "); got != tt.want {
+				t.Errorf("modules = %s, want %s", got, tt.want)
+			}
+			if got := strings.Join(common, ","); got != tt.common {
+				t.Errorf("common = %s, want %s", got, tt.common)
+			}
+			for target, want := range tt.modules {
+				names := make([]string, 0, 2)
+				for _, aModule := range aMapPodfile.TargetModules(target) {
+					names = append(names, aModule.Name)
+				}
+				if got := strings.Join(names, ","); got != want {
+					t.Errorf("TargetModules(%s) = %s, want %s", target, got, want)
+				}
+			}
+		})
+	}
+}
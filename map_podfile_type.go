@@ -1,5 +1,7 @@
 package pod
 
+import "sync"
+
 const (
 	TagEmptyVersion   = ""
 	TagUnknownVersion = "*"
@@ -12,27 +14,204 @@ const (
 	StateMapPodfileModuleImplicit = uint(1) << 3
 )
 
+// Ways a version of a MapPodfileModule is picked
+const (
+	PickByPodfile = "podfile"
+	PickByLocal   = "local"
+	PickByRule    = "rule"
+	PickByQuery   = "query"
+	PickByCluster = "cluster"
+	PickByPolicy  = "policy"
+)
+
+// Modes of an UpdatePolicy
+const (
+	UpdateModeConservative = "conservative"
+	UpdateModeAll          = "all"
+	UpdateModeListed       = "listed"
+)
+
+// Caps of an UpdatePolicy, how far a module may move from its OriginV
+const (
+	UpdateCapMajor = "major"
+	UpdateCapMinor = "minor"
+	UpdateCapPatch = "patch"
+)
+
+// PrereleaseConstraint is added to the queries of the modules allowed to
+// take a prerelease, NewPodQueryVersionFunc only returns prereleases when a
+// constraint names one.
+const PrereleaseConstraint = ">= 0.0.0-0"
+
 type QueryVersionFunc func(module string, constraits []string) (string, error)
 type QueryDependsFunc func(module, version string) ([]*DependBase, error)
 
+const DefaultMaxEvolutionTimes = uint(100)
+
+const (
+	EvolutionErrorMaxIterations = "max_iterations"
+	EvolutionErrorOscillation   = "oscillation"
+	EvolutionErrorCanceled      = "canceled"
+	EvolutionErrorPolicy        = "policy"
+)
+
+// UpdatePolicy decides how MapPodfile moves modules away from their OriginV.
+//
+// Mode UpdateModeConservative (or empty) keeps OriginV unless a constraint
+// forces a change, UpdateModeAll picks the newest allowed version of every
+// module and UpdateModeListed does so only for the base names in Listed.
+// Caps limits the move per base name, DefaultCap applies to the others and
+// empty means UpdateCapMajor. Prerelease allows prereleases for every
+// module, PrereleaseModules for some. Exclude keeps modules at or below
+// their OriginV. Caps and Exclude are hard constraints, Evolution fails with
+// EvolutionErrorPolicy when a dependency needs more. Force sets versions or
+// constraints per base name, as the former updateRule map did, and wins over
+// everything else.
+type UpdatePolicy struct {
+	Mode              string
+	Listed            []string
+	Caps              map[string]string
+	DefaultCap        string
+	Prerelease        bool
+	PrereleaseModules []string
+	Exclude           []string
+	Force             map[string]string
+}
+
+// MapPodfile resolves the modules of one or more Podfile targets to a single
+// version each. MaxEvolutionTimes bounds the iterations of Evolution, 0
+// means DefaultMaxEvolutionTimes. QueryConcurrency is the number of queries
+// Evolution runs at once, 0 or 1 runs them one by one; above 1 the query
+// funcs must be safe for concurrent use, as NewPodQueryVersionFunc and
+// NewPodQueryDependsFunc are. Query results are memoized either way.
 type MapPodfile struct {
-	Map map[string]*MapPodfileModule
+	Map               map[string]*MapPodfileModule
+	MaxEvolutionTimes uint
+	QueryConcurrency  int
 
 	sameParentMap    map[string]map[string]*MapPodfileModule
 	updateRule       map[string]string
+	policy           *UpdatePolicy
 	queryVersionFunc QueryVersionFunc
 	queryDependsFunc QueryDependsFunc
 	evolutionTimes   uint
+	provenance       map[string]*MapPodfileProvenance
+	targets          []string
+	targetModules    map[string][]string // modules written in each target
+	memoMutex        sync.Mutex
+	versionMemo      map[string]*p_query_result
+	dependsMemo      map[string]*p_query_result
+}
+
+// MapPodfileProvenance records how the version of a module was chosen:
+// every constraint put on it and every version picked for it, in order.
+type MapPodfileProvenance struct {
+	Module      string
+	Constraints []*MapPodfileConstraint
+	Picks       []*MapPodfilePick
 }
 
+// MapPodfileConstraint is a constraint added by Source at SourceVersion,
+// Source is "Podfile" for the requirement written in the Podfile.
+type MapPodfileConstraint struct {
+	Constraint    string
+	Source        string
+	SourceVersion string
+	Iteration     uint
+}
+
+// MapPodfilePick is a version picked for a module, By is one of the PickBy*
+// constants and Constraints are the ones the pick had to satisfy.
+type MapPodfilePick struct {
+	Version     string
+	By          string
+	Constraints []string
+	Iteration   uint
+}
+
+// EvolutionError is returned when Evolution gives up. Changing lists the
+// modules whose version was still changing, Reason is one of the
+// EvolutionError* constants and Err is the context error when canceled.
+// For EvolutionErrorPolicy, Module is the module no version of which meets
+// both its Constraints and the rule of the UpdatePolicy named by Policy.
+type EvolutionError struct {
+	Reason      string
+	Iterations  uint
+	Changing    []string
+	Err         error
+	Module      string
+	Policy      string
+	Constraints []string
+}
+
+// Gaps between two versions in an OutdatedReport
+const (
+	OutdatedGapNone    = "none"
+	OutdatedGapPatch   = "patch"
+	OutdatedGapMinor   = "minor"
+	OutdatedGapMajor   = "major"
+	OutdatedGapUnknown = "unknown"
+)
+
+// OutdatedBlockerPolicy is the Module of the blockers put by the UpdatePolicy
+const OutdatedBlockerPolicy = "UpdatePolicy"
+
+// OutdatedReport compares the versions of the modules of a MapPodfile like
+// `pod outdated`, local modules are left out.
+type OutdatedReport struct {
+	Modules []*OutdatedModule `json:"modules"`
+}
+
+// OutdatedModule shows the Current (OriginV), Resolvable (UsefulV) and
+// Latest (LatestV) versions of a module. Latest is the newest version of the
+// repos, neither the Podfile nor the caps of the UpdatePolicy apply. Gap is
+// from Current, or Resolvable when Current is unknown, to Latest. Blockers
+// are the constraints Latest does not match.
+type OutdatedModule struct {
+	Name          string             `json:"name"`
+	Current       string             `json:"current"`
+	Resolvable    string             `json:"resolvable"`
+	Latest        string             `json:"latest"`
+	Gap           string             `json:"gap"`
+	ResolvableGap string             `json:"resolvable_gap"`
+	Outdated      bool               `json:"outdated"`
+	Blockers      []*OutdatedBlocker `json:"blockers,omitempty"`
+}
+
+// OutdatedBlocker is a constraint put by Module at Version, Module is
+// "Podfile" for a requirement of the Podfile and OutdatedBlockerPolicy for a
+// cap of the UpdatePolicy.
+type OutdatedBlocker struct {
+	Module     string `json:"module"`
+	Version    string `json:"version,omitempty"`
+	Constraint string `json:"constraint"`
+}
+
+// MapPodfileModule is a module of the resolution. NewestV is the newest
+// version the UpdatePolicy allows, LatestV the newest one regardless of the
+// Podfile and the policy. Targets are the targets linking it, filled when
+// Evolution succeeds.
 type MapPodfileModule struct {
 	Name    string
 	OriginV string
 	UsefulV string
 	NewestV string
+	LatestV string
+	Targets []string
 
 	beDepended  int
 	state       uint
 	constraints []string // version queue
 	verDepMap   map[string][]*DependBase
 }
+
+// *** Private ***
+
+// p_query_result is a memoized query, once makes concurrent callers of the
+// same key wait for a single call.
+type p_query_result struct {
+	once    sync.Once
+	version string
+	depends []*DependBase
+	err     error
+}
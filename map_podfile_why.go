@@ -0,0 +1,113 @@
+package pod
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// ** MapPodfile Provenance Impl **
+
+// Provenance returns the record of the constraints and picks of module, it
+// is kept for modules removed from Map by the evolution as well.
+func (s *MapPodfile) Provenance(module string) *MapPodfileProvenance {
+	if s.provenance == nil {
+		return nil
+	}
+	return s.provenance[module]
+}
+
+// Why explains the version chosen for module.
+func (s *MapPodfile) Why(module string) string {
+	p := s.Provenance(module)
+	aModule, inMap := s.Map[module]
+	if p == nil && !inMap {
+		return module + " is not part of the resolution\n"
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString(module)
+	if inMap {
+		buffer.WriteString(" " + aModule.UsefulV)
+		if aModule.IsImplicit() {
+			buffer.WriteString(" (implicit)")
+		}
+	} else {
+		buffer.WriteString(" (merged into its parent pod)")
+	}
+	buffer.WriteString("\n")
+	if p == nil {
+		return buffer.String()
+	}
+	if len(p.Constraints) > 0 {
+		buffer.WriteString("  constraints:\n")
+		for _, c := range p.Constraints {
+			buffer.WriteString("    " + c.String() + "\n")
+		}
+	}
+	if len(p.Picks) > 0 {
+		buffer.WriteString("  picks:\n")
+		for _, pick := range p.Picks {
+			buffer.WriteString("    " + pick.String() + "\n")
+		}
+		last := p.Picks[len(p.Picks)-1]
+		buffer.WriteString("  chosen by " + last.By)
+		if last.By == PickByRule {
+			buffer.WriteString(" (update rule overrides the constraints)")
+		}
+		buffer.WriteString("\n")
+	}
+	return buffer.String()
+}
+
+func (s *MapPodfile) provenanceOf(module string) *MapPodfileProvenance {
+	if s.provenance == nil {
+		s.provenance = make(map[string]*MapPodfileProvenance)
+	}
+	p, ok := s.provenance[module]
+	if !ok {
+		p = &MapPodfileProvenance{Module: module}
+		s.provenance[module] = p
+	}
+	return p
+}
+
+// recordConstraint keeps the first iteration in which source added c.
+func (s *MapPodfile) recordConstraint(module, c, source, sourceVersion string) {
+	if c == TagEmptyVersion || c == TagUnknownVersion {
+		return
+	}
+	p := s.provenanceOf(module)
+	for _, exist := range p.Constraints {
+		if exist.Constraint == c && exist.Source == source && exist.SourceVersion == sourceVersion {
+			return
+		}
+	}
+	p.Constraints = append(p.Constraints, &MapPodfileConstraint{Constraint: c, Source: source, SourceVersion: sourceVersion, Iteration: s.evolutionTimes})
+}
+
+func (s *MapPodfile) recordPick(module, version, by string, constraints []string) {
+	p := s.provenanceOf(module)
+	pick := &MapPodfilePick{Version: version, By: by, Iteration: s.evolutionTimes}
+	if len(constraints) > 0 {
+		pick.Constraints = append([]string(nil), constraints...)
+	}
+	p.Picks = append(p.Picks, pick)
+}
+
+// ** MapPodfileConstraint Impl **
+func (s *MapPodfileConstraint) String() string {
+	from := s.Source
+	if s.SourceVersion != "" {
+		from += " " + s.SourceVersion
+	}
+	return s.Constraint + " from " + from + " (iteration " + strconv.FormatUint(uint64(s.Iteration), 10) + ")"
+}
+
+// ** MapPodfilePick Impl **
+func (s *MapPodfilePick) String() string {
+	res := "iteration " + strconv.FormatUint(uint64(s.Iteration), 10) + ": " + s.Version + " by " + s.By
+	if len(s.Constraints) > 0 {
+		res += " [" + strings.Join(s.Constraints, ", ") + "]"
+	}
+	return res
+}
@@ -0,0 +1,109 @@
+package pod
+
+import (
+	"testing"
+)
+
+func TestMapPodfileWhy(t *testing.T) {
+	index := testIndex{
+		"A 1.0": {"C >= 1.5"},
+		"A 1.1": {"C >= 1.5"},
+		"C 1.0": nil, "C 1.5": nil, "C 2.0": nil,
+	}
+	pinned := "target 'App' do\n  pod 'A', '1.0'\nend\n"
+	tests := []struct {
+		name   string
+		source string
+		rule   map[string]string
+		module string
+		want   string
+	}{
+		{
+			name:   "podfile requirement",
+			source: "target 'App' do\n  pod 'A', '~> 1.0'\nend\n",
+			module: "A",
+			want: `A 1.1
+  constraints:
+    ~> 1.0 from Podfile (iteration 0)
+  picks:
+    iteration 0: 1.1 by query
+  chosen by query
+`,
+		},
+		{
+			name:   "podfile version",
+			source: pinned,
+			module: "A",
+			want: `A 1.0
+  constraints:
+    1.0 from Podfile (iteration 0)
+  picks:
+    iteration 0: 1.0 by podfile
+  chosen by podfile
+`,
+		},
+		{
+			name:   "transitive constraint",
+			source: pinned,
+			module: "C",
+			want: `C 2.0 (implicit)
+  constraints:
+    >= 1.5 from A 1.0 (iteration 1)
+  picks:
+    iteration 2: 2.0 by query [>= 1.5]
+  chosen by query
+`,
+		},
+		{
+			name:   "update rule",
+			source: pinned,
+			rule:   map[string]string{"C": "1.5"},
+			module: "C",
+			want: `C 1.5 (implicit)
+  constraints:
+    >= 1.5 from A 1.0 (iteration 1)
+  picks:
+    iteration 1: 1.5 by rule
+  chosen by rule (update rule overrides the constraints)
+`,
+		},
+		{
+			name:   "update rule against a constraint",
+			source: pinned,
+			rule:   map[string]string{"C": "1.0"},
+			module: "C",
+			want: `C 2.0 (implicit)
+  constraints:
+    >= 1.5 from A 1.0 (iteration 1)
+  picks:
+    iteration 1: 1.0 by rule
+    iteration 2: 2.0 by query [>= 1.5]
+  chosen by query
+`,
+		},
+		{
+			name:   "missing",
+			source: pinned,
+			module: "Missing",
+			want:   "Missing is not part of the resolution\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aPodfile, err := NewPodfileWithBytes("Podfile", []byte(tt.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			aMapPodfile, err := NewMapPodfile(aPodfile, "App", tt.rule, index.queryVersion, index.queryDepends)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := aMapPodfile.Evolution(nil); err != nil {
+				t.Fatal(err)
+			}
+			if got := aMapPodfile.Why(tt.module); got != tt.want {
+				t.Errorf("Why(%s) =\n%s\nwant\n%s", tt.module, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package pod
 
 import (
-	"os/exec"
-
+	"context"
+	"fmt"
+	"io/ioutil"
 	"path"
+	"strings"
 
 	fdt "github.com/go-hayden-base/foundation"
+	"github.com/go-hayden-base/fs"
 	ver "github.com/go-hayden-base/version"
 	yaml "gopkg.in/yaml.v2"
 )
@@ -21,9 +24,9 @@ func (s *Podfile) FillLocalModuleDepends(threadNum int, logFunc func(success boo
 	}
 
 	dir := path.Dir(s.FilePath)
-	asyncFunc := func(aModule *PodfileModule) {
-		specPath := path.Join(dir, aModule.SpecPath)
-		aSpec, err := ReadSpec(specPath)
+	asyncFunc := func(aModule *PodfileModule, platform string) {
+		specPath, _ := aModule.LocalSpecFile(dir)
+		aSpec, err := ReadSpecWithRunner(context.Background(), s.Runner, specPath)
 		if err != nil {
 			if logFunc != nil {
 				logFunc(false, "解析Spec失败: "+specPath+" 原因: "+err.Error())
@@ -33,18 +36,19 @@ func (s *Podfile) FillLocalModuleDepends(threadNum int, logFunc func(success boo
 				logFunc(true, "解析Spec成功: "+specPath)
 			}
 			aModule.V = aSpec.Version
-			aModule.Depends = getAllDependsFromSpec(aSpec)
+			aModule.Depends = getAllDependsFromSpec(aSpec, platform)
 		}
 		c <- true
 	}
 
 	for _, aTarget := range s.Targets {
+		platform, _ := aTarget.EffectivePlatform()
 		for _, aModule := range aTarget.Modules {
 			if !aModule.IsLocal() {
 				continue
 			}
 			<-c
-			go asyncFunc(aModule)
+			go asyncFunc(aModule, platform)
 		}
 	}
 	for i := 0; i < threadNum; i++ {
@@ -57,6 +61,9 @@ func (s *Podfile) HasModule(name string, inTargets []string) bool {
 		if inTargets != nil && !fdt.SliceContainsStr(aTarget.Name, inTargets) {
 			continue
 		}
+		if inTargets != nil && aTarget.EffectiveModuleWithName(name) != nil {
+			return true
+		}
 		if aTarget.HasModule(name) {
 			return true
 		}
@@ -65,6 +72,9 @@ func (s *Podfile) HasModule(name string, inTargets []string) bool {
 }
 
 func (s *Podfile) TargetWithName(name string) *PodfileTarget {
+	if name == PodfileRootTargetName {
+		return s.Root
+	}
 	for _, aTarget := range s.Targets {
 		if aTarget.Name == name {
 			return aTarget
@@ -81,6 +91,9 @@ func (s *Podfile) VersionOfModule(name string, inTargets []string) (string, bool
 			continue
 		}
 		aModule := aTarget.ModuleWithFuzzyName(name)
+		if aModule == nil && inTargets != nil {
+			aModule = aTarget.effectiveModuleWithFuzzyName(name)
+		}
 		if aModule != nil {
 			if !exist {
 				exist = true
@@ -113,14 +126,103 @@ func (s *Podfile) EnumerateAllModules(f func(target, module, version string)) {
 
 func (s *Podfile) Print() {
 	for _, aTarget := range s.Targets {
-		println("-> " + aTarget.Name)
+		indent := ""
+		for p := aTarget.Parent; p != nil; p = p.Parent {
+			indent += "   "
+		}
+		name := aTarget.Name
+		if aTarget.Abstract {
+			name += " (abstract)"
+		}
+		println(indent + "-> " + name)
 		for _, aModule := range aTarget.Modules {
-			println("   -", aModule.Name, aModule.Version, aModule.Type, aModule.SpecPath)
+			println(indent+"   -", aModule.Name(), aModule.Version(), aModule.Type, aModule.SpecPath)
 		}
 	}
 }
 
 // ** Target Impl **
+func (s *PodfileTarget) IsRoot() bool {
+	return s.Parent == nil
+}
+
+func (s *PodfileTarget) AddChild(aTarget *PodfileTarget) {
+	aTarget.Parent = s
+	s.Children = append(s.Children, aTarget)
+}
+
+// EffectivePlatform returns the platform of the target, inherited from the
+// closest ancestor that declares one.
+func (s *PodfileTarget) EffectivePlatform() (string, string) {
+	for t := s; t != nil; t = t.Parent {
+		if t.Platform != "" {
+			return t.Platform, t.PlatformVersion
+		}
+	}
+	return "", ""
+}
+
+// IsExclusive reports whether the target does not inherit the dependencies of
+// its parent, as CocoaPods does for the root, for `inherit! :none` or
+// `inherit! :search_paths` and for a child on another platform.
+func (s *PodfileTarget) IsExclusive() bool {
+	if s.Parent == nil {
+		return true
+	}
+	if s.Inheritance != "" && s.Inheritance != PodfileInheritanceComplete {
+		return true
+	}
+	platform, _ := s.EffectivePlatform()
+	parentPlatform, _ := s.Parent.EffectivePlatform()
+	return platform != parentPlatform
+}
+
+// EffectiveModules returns the modules declared by the target followed by
+// the ones it inherits. A module declared again overrides the inherited one.
+func (s *PodfileTarget) EffectiveModules() []*PodfileModule {
+	res := make([]*PodfileModule, 0, len(s.Modules))
+	dup := make(map[string]bool)
+	for t := s; t != nil; t = t.Parent {
+		for _, aModule := range t.Modules {
+			if dup[aModule.N] {
+				continue
+			}
+			dup[aModule.N] = true
+			res = append(res, aModule)
+		}
+		if t.IsExclusive() {
+			break
+		}
+	}
+	return res
+}
+
+func (s *PodfileTarget) EffectiveModuleWithName(name string) *PodfileModule {
+	for _, aModule := range s.EffectiveModules() {
+		if aModule.N == name {
+			return aModule
+		}
+	}
+	return nil
+}
+
+func (s *PodfileTarget) effectiveModuleWithFuzzyName(name string) *PodfileModule {
+	baseName := fdt.StrSplitFirst(name, "/")
+	for _, aModule := range s.EffectiveModules() {
+		if fdt.StrSplitFirst(aModule.N, "/") == baseName {
+			return aModule
+		}
+	}
+	return nil
+}
+
+func (s *PodfileTarget) enumerate(f func(aTarget *PodfileTarget)) {
+	f(s)
+	for _, child := range s.Children {
+		child.enumerate(f)
+	}
+}
+
 func (s *PodfileTarget) ModuleWithName(name string) *PodfileModule {
 	for _, aModule := range s.Modules {
 		if aModule.N == name {
@@ -150,14 +252,167 @@ func (s *PodfileTarget) HasModule(name string) bool {
 	return has
 }
 
+// AddModule appends a module with the given version requirement, or updates
+// the requirement when the target already declares it.
+func (s *PodfileTarget) AddModule(name, version string) *PodfileModule {
+	if aModule := s.ModuleWithName(name); aModule != nil {
+		aModule.setVersion(version)
+		return aModule
+	}
+	aModule := new(PodfileModule)
+	aModule.N = name
+	aModule.setVersion(version)
+	s.Modules = append(s.Modules, aModule)
+	return aModule
+}
+
+func (s *PodfileTarget) RemoveModule(name string) bool {
+	for idx, aModule := range s.Modules {
+		if aModule.N == name {
+			s.Modules = append(s.Modules[:idx], s.Modules[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (s *PodfileTarget) SetModuleVersion(name, version string) bool {
+	aModule := s.ModuleWithName(name)
+	if aModule == nil {
+		return false
+	}
+	aModule.setVersion(version)
+	return true
+}
+
+// ApplyMapPodfile pins every declared, non local module of the target to the
+// version chosen by aMapPodfile. Implicit modules are not added.
+func (s *PodfileTarget) ApplyMapPodfile(aMapPodfile *MapPodfile) {
+	if aMapPodfile == nil {
+		return
+	}
+	for _, aModule := range s.Modules {
+		if aModule.IsLocal() {
+			continue
+		}
+		aMapModule, ok := aMapPodfile.Map[aModule.N]
+		if !ok || aMapModule.UsefulV == TagEmptyVersion || aMapModule.UsefulV == TagUnknownVersion {
+			continue
+		}
+		aModule.setVersion(aMapModule.UsefulV)
+	}
+}
+
 // ** PodfileModule Impl **
 func (s *PodfileModule) IsLocal() bool {
 	return s.SpecPath != ""
 }
 
+// VersionRequirements returns every version requirement of the module, V in
+// place of the first one.
+func (s *PodfileModule) VersionRequirements() []string {
+	res := make([]string, 0, len(s.Requirements)+1)
+	if s.V != TagEmptyVersion && s.V != TagUnknownVersion {
+		res = append(res, s.V)
+	}
+	if len(s.Requirements) > 1 {
+		res = append(res, s.Requirements[1:]...)
+	}
+	return res
+}
+
+// setVersion replaces all the requirements with version.
+func (s *PodfileModule) setVersion(version string) {
+	s.V = version
+	s.Requirements = nil
+	if version != TagEmptyVersion && version != TagUnknownVersion {
+		s.Requirements = []string{version}
+	}
+}
+
+// LocalSpecFile returns the spec file of a local module, the podspec given by
+// :podspec or the spec named after the pod inside the :path directory.
+func (s *PodfileModule) LocalSpecFile(podfileDir string) (string, bool) {
+	if !s.IsLocal() {
+		return "", false
+	}
+	specPath := s.SpecPath
+	if !path.IsAbs(specPath) {
+		specPath = path.Join(podfileDir, specPath)
+	}
+	if s.Type == "podspec" {
+		if fs.DirectoryExists(specPath) {
+			return findSpecFileInDir(specPath, fdt.StrSplitFirst(s.N, "/"))
+		}
+		return specPath, fs.FileExists(specPath)
+	}
+	if !fs.DirectoryExists(specPath) {
+		return specPath, fs.FileExists(specPath)
+	}
+	return findSpecFileInDir(specPath, fdt.StrSplitFirst(s.N, "/"))
+}
+
+func (s *PodfileModule) fillTypeWithOptions() {
+	opts := s.Options
+	if opts == nil {
+		return
+	}
+	switch {
+	case opts.Path != "":
+		s.Type, s.SpecPath = "path", opts.Path
+	case opts.Podspec != "" && !strings.Contains(opts.Podspec, "://"):
+		s.Type, s.SpecPath = "podspec", opts.Podspec
+	case opts.Podspec != "":
+		s.Type = "podspec"
+	case opts.Git != "":
+		s.Type = "git"
+	}
+}
+
+// expandSubspecs turns `pod 'Foo', :subspecs => ['A', 'B']` into the modules
+// Foo/A and Foo/B, the same way CocoaPods stores them.
+func (s *PodfileModule) expandSubspecs() []*PodfileModule {
+	if s.Options == nil || len(s.Options.Subspecs) == 0 {
+		return []*PodfileModule{s}
+	}
+	res := make([]*PodfileModule, 0, len(s.Options.Subspecs))
+	for _, sub := range s.Options.Subspecs {
+		aModule := new(PodfileModule)
+		*aModule = *s
+		aModule.N = s.N + "/" + sub
+		opts := *s.Options
+		opts.Subspecs = nil
+		opts.Configurations = append([]string(nil), s.Options.Configurations...)
+		aModule.Options = &opts
+		aModule.Requirements = append([]string(nil), s.Requirements...)
+		aModule.subspecsOf = s
+		res = append(res, aModule)
+	}
+	return res
+}
+
 // ** Func Public **
+
+// NewPodfile parses the Podfile natively and falls back to `pod ipc podfile`
+// when the file uses Ruby that ParsePodfile can not evaluate.
 func NewPodfile(filePath string) (*Podfile, error) {
-	b, err := exec.Command("pod", "ipc", "podfile", filePath).Output()
+	return NewPodfileWithRunner(context.Background(), nil, filePath)
+}
+
+func NewPodfileWithRunner(ctx context.Context, runner CommandRunner, filePath string) (*Podfile, error) {
+	aPodfile, err := ParsePodfile(filePath)
+	if err == nil {
+		aPodfile.Runner = runner
+		return aPodfile, nil
+	}
+	if _, ok := err.(*PodfileUnsupportedError); !ok {
+		return nil, err
+	}
+	return NewPodfileWithIPC(ctx, runner, filePath)
+}
+
+func NewPodfileWithIPC(ctx context.Context, runner CommandRunner, filePath string) (*Podfile, error) {
+	b, err := commandRunnerOrDefault(runner).RunPod(ctx, "ipc", "podfile", filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -166,36 +421,82 @@ func NewPodfile(filePath string) (*Podfile, error) {
 	if err != nil {
 		return nil, err
 	}
+	aPodfile := newPodfileWithDefinition(filePath, pf)
+	aPodfile.Runner = runner
+	return aPodfile, nil
+}
+
+// ** Func Private **
+func newPodfileWithDefinition(filePath string, pf *p_podfile) *Podfile {
 	aPodfile := new(Podfile)
+	aPodfile.Sources = pf.Sources
 	aPodfile.Targets = make([]*PodfileTarget, 0, 5)
 	for _, a := range pf.Target_definitions {
-		// 读取子Targert
-		for _, b := range a.Children {
-			aTarget := new(PodfileTarget)
-			aTarget.Name = b.Name
-			aTarget.Modules = generateModules(b.Dependencies)
-			aPodfile.Targets = append(aPodfile.Targets, aTarget)
+		aTarget := newPodfileTarget(a)
+		if aPodfile.Root == nil {
+			aPodfile.Root = aTarget
 		}
+		aTarget.enumerate(func(t *PodfileTarget) {
+			aPodfile.Targets = append(aPodfile.Targets, t)
+		})
+	}
+	aPodfile.FilePath = filePath
+	return aPodfile
+}
 
-		// 读取主Target
-		if len(a.Dependencies) > 0 {
-			aTarget := new(PodfileTarget)
-			aTarget.Name = "*"
-			aTarget.Modules = generateModules(a.Dependencies)
-			aPodfile.Targets = append(aPodfile.Targets, aTarget)
+func newPodfileTarget(def *p_target_definition) *PodfileTarget {
+	aTarget := new(PodfileTarget)
+	aTarget.Name = def.Name
+	aTarget.Abstract = def.Abstract
+	aTarget.Inheritance = def.Inheritance
+	aTarget.Statements = def.statements
+	aTarget.LeadingStatements = def.leadingStatements
+	aTarget.EndComments = def.endComments
+	if def.comments != nil {
+		aTarget.Comments, aTarget.LineComment = def.comments.above, def.comments.inline
+	}
+	aTarget.Modules = make([]*PodfileModule, 0, len(def.Dependencies))
+	for idx, dep := range def.Dependencies {
+		modules := generateModules([]interface{}{dep})
+		if idx < len(def.dependencyComments) && len(modules) > 0 {
+			modules[0].Comments, modules[0].LineComment = def.dependencyComments[idx].above, def.dependencyComments[idx].inline
 		}
+		aTarget.Modules = append(aTarget.Modules, modules...)
 	}
-	aPodfile.FilePath = filePath
-	return aPodfile, nil
+	applyTargetOptions(def, aTarget.Modules)
+	for _, child := range def.Children {
+		aTarget.AddChild(newPodfileTarget(child))
+	}
+	switch p := def.Platform.(type) {
+	case string:
+		aTarget.Platform = p
+	case map[interface{}]interface{}:
+		for k, v := range p {
+			aTarget.Platform, _ = k.(string)
+			if v != nil {
+				aTarget.PlatformVersion = fmt.Sprint(v)
+			}
+		}
+	}
+	switch u := def.Uses_frameworks.(type) {
+	case bool:
+		aTarget.UseFrameworks = u
+	case map[interface{}]interface{}:
+		aTarget.UseFrameworks = true
+		aTarget.UseFrameworksOptions = make(map[string]string, len(u))
+		for k, v := range u {
+			aTarget.UseFrameworksOptions[strings.TrimPrefix(fmt.Sprint(k), ":")] = strings.TrimPrefix(fmt.Sprint(v), ":")
+		}
+	}
+	return aTarget
 }
 
-// ** Func Private **
-func getAllDependsFromSpec(aSpec *Spec) []*DependBase {
+func getAllDependsFromSpec(aSpec *Spec, platform string) []*DependBase {
 	if aSpec == nil {
 		return nil
 	}
 	mapDup := make(map[string]*DependBase)
-	aSpec.enumerateDepends(func(module, depend, version string) {
+	aSpec.enumerateDepends(platform, func(module, depend, version string) {
 		_, ok := mapDup[depend]
 		if ok {
 			return
@@ -236,31 +537,146 @@ func generateModules(module []interface{}) []*PodfileModule {
 			}
 			aModule := new(PodfileModule)
 			aModule.N = ks
-			modules = append(modules, aModule)
+			varr, _ := v.([]interface{})
+			for _, d := range varr {
+				switch x := d.(type) {
+				case string:
+					aModule.Requirements = append(aModule.Requirements, x)
+				case map[interface{}]interface{}:
+					aModule.Options = newPodfileModuleOptions(x)
+				}
+			}
+			if len(aModule.Requirements) > 0 {
+				aModule.V = aModule.Requirements[0]
+			}
+			aModule.fillTypeWithOptions()
+			modules = append(modules, aModule.expandSubspecs()...)
+		}
+	}
+	return modules
+}
 
-			varr, ok := v.([]interface{})
-			if !ok {
-				continue
+func newPodfileModuleOptions(m map[interface{}]interface{}) *PodfileModuleOptions {
+	opts := new(PodfileModuleOptions)
+	for k, v := range m {
+		ks, ok := k.(string)
+		if !ok {
+			continue
+		}
+		key := strings.TrimPrefix(ks, ":")
+		str, _ := v.(string)
+		switch key {
+		case "path":
+			opts.Path = str
+		case "podspec":
+			opts.Podspec = str
+		case "git":
+			opts.Git = str
+		case "branch":
+			opts.Branch = str
+		case "tag":
+			opts.Tag = str
+		case "commit":
+			opts.Commit = str
+		case "source":
+			opts.Source = str
+		case "subspecs":
+			opts.Subspecs = interfaceToStrings(v)
+		case "testspecs":
+			opts.Testspecs = interfaceToStrings(v)
+		case "configurations", "configuration":
+			opts.Configurations = append(opts.Configurations, interfaceToStrings(v)...)
+		case "modular_headers":
+			if b, ok := v.(bool); ok {
+				opts.ModularHeaders = &b
 			}
-			if len(varr) == 0 {
+		case "inhibit_warnings":
+			if b, ok := v.(bool); ok {
+				opts.InhibitWarnings = &b
+			}
+		default:
+			if opts.Other == nil {
+				opts.Other = make(map[string]interface{})
+			}
+			opts.Other[key] = v
+		}
+	}
+	return opts
+}
+
+// applyTargetOptions restores the per pod options that `pod ipc podfile`
+// stores on the target definition instead of the dependency.
+func applyTargetOptions(def *p_target_definition, modules []*PodfileModule) {
+	optionsOf := func(name string) []*PodfileModuleOptions {
+		res := make([]*PodfileModuleOptions, 0, 1)
+		for _, aModule := range modules {
+			if fdt.StrSplitFirst(aModule.N, "/") != name {
 				continue
 			}
-			d := varr[0]
-			switch d.(type) {
-			case string:
-				aModule.V = d.(string)
-			case map[interface{}]interface{}:
-				x := d.(map[interface{}]interface{})
-				for kk, vv := range x {
-					kkk, okk := kk.(string)
-					vvv, okv := vv.(string)
-					if okk && okv {
-						aModule.Type = kkk
-						aModule.SpecPath = vvv
-					}
+			if aModule.Options == nil {
+				aModule.Options = new(PodfileModuleOptions)
+			}
+			res = append(res, aModule.Options)
+		}
+		return res
+	}
+	for config, pods := range def.Configuration_pod_whitelist {
+		for _, name := range pods {
+			for _, opts := range optionsOf(name) {
+				if !fdt.SliceContainsStr(config, opts.Configurations) {
+					opts.Configurations = append(opts.Configurations, config)
 				}
 			}
 		}
 	}
-	return modules
+	flagFor := func(m map[string]interface{}, set func(opts *PodfileModuleOptions, b bool)) {
+		for _, name := range interfaceToStrings(m["for_pods"]) {
+			for _, opts := range optionsOf(name) {
+				set(opts, true)
+			}
+		}
+		for _, name := range interfaceToStrings(m["not_for_pods"]) {
+			for _, opts := range optionsOf(name) {
+				set(opts, false)
+			}
+		}
+	}
+	flagFor(def.Inhibit_warnings, func(opts *PodfileModuleOptions, b bool) { opts.InhibitWarnings = &b })
+	flagFor(def.Use_modular_headers, func(opts *PodfileModuleOptions, b bool) { opts.ModularHeaders = &b })
+}
+
+func findSpecFileInDir(dir, name string) (string, bool) {
+	for _, fileName := range []string{name + ".podspec.json", name + ".podspec"} {
+		if p := path.Join(dir, fileName); fs.FileExists(p) {
+			return p, true
+		}
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	for _, fi := range files {
+		if !fi.IsDir() && (strings.HasSuffix(fi.Name(), ".podspec") || strings.HasSuffix(fi.Name(), ".podspec.json")) {
+			return path.Join(dir, fi.Name()), true
+		}
+	}
+	return "", false
+}
+
+func interfaceToStrings(v interface{}) []string {
+	switch x := v.(type) {
+	case string:
+		return []string{x}
+	case []interface{}:
+		res := make([]string, 0, len(x))
+		for _, item := range x {
+			if s, ok := item.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	case []string:
+		return x
+	}
+	return nil
 }
@@ -0,0 +1,981 @@
+package pod
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// ** Podfile Lexer **
+const (
+	tokEOF = iota
+	tokNewline
+	tokIdent
+	tokLabel
+	tokString
+	tokSymbol
+	tokNumber
+	tokWords
+	tokPunct
+	tokOther
+)
+
+type rbToken struct {
+	kind   int
+	val    string
+	words  []string
+	line   int
+	interp bool
+}
+
+type rbLexer struct {
+	src    []byte
+	pos    int
+	line   int
+	tokens []*rbToken
+
+	// Text of the comments by line, from the # to the end of the line
+	comments map[int]string
+
+	// End of the heredoc bodies started on the current line
+	heredocEnd   int
+	heredocLines int
+}
+
+func rbTokenize(src []byte) []*rbToken {
+	return rbLex(src).tokens
+}
+
+func rbLex(src []byte) *rbLexer {
+	l := &rbLexer{src: src, line: 1, comments: make(map[int]string)}
+	l.run()
+	return l
+}
+
+func (s *rbLexer) emit(kind int, val string) *rbToken {
+	t := &rbToken{kind: kind, val: val, line: s.line}
+	s.tokens = append(s.tokens, t)
+	return t
+}
+
+func (s *rbLexer) peekByte(offset int) byte {
+	if s.pos+offset < len(s.src) {
+		return s.src[s.pos+offset]
+	}
+	return 0
+}
+
+func (s *rbLexer) atLineStart() bool {
+	return s.pos == 0 || s.src[s.pos-1] == '\n'
+}
+
+func (s *rbLexer) run() {
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			s.pos++
+		case c == '\\' && s.peekByte(1) == '\n':
+			s.pos += 2
+			s.line++
+		case c == '\n' || c == ';':
+			if n := len(s.tokens); n == 0 || s.tokens[n-1].kind != tokNewline {
+				s.emit(tokNewline, "\n")
+			}
+			if c == '\n' {
+				s.line++
+			}
+			s.pos++
+			if c == '\n' && s.heredocEnd > 0 {
+				s.pos, s.line = s.heredocEnd, s.line+s.heredocLines
+				s.heredocEnd, s.heredocLines = 0, 0
+			}
+		case c == '#':
+			start := s.pos
+			for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+				s.pos++
+			}
+			s.comments[s.line] = strings.TrimRight(string(s.src[start:s.pos]), " \t\r")
+		case c == '=' && s.atLineStart() && strings.HasPrefix(string(s.src[s.pos:]), "=begin"):
+			s.skipEmbeddedDoc()
+		case c == '\'' || c == '"':
+			s.lexString(c)
+		case c == ':' && s.peekByte(1) == ':':
+			s.emit(tokPunct, "::")
+			s.pos += 2
+		case c == ':' && (isIdentStart(s.peekByte(1)) || s.peekByte(1) == '"' || s.peekByte(1) == '\''):
+			s.pos++
+			if q := s.src[s.pos]; q == '"' || q == '\'' {
+				s.lexString(q)
+				s.tokens[len(s.tokens)-1].kind = tokSymbol
+			} else {
+				s.emit(tokSymbol, s.lexIdent())
+			}
+		case c == '%' && (s.peekByte(1) == 'w' || s.peekByte(1) == 'i') && isWordsOpen(s.peekByte(2)):
+			s.lexWords()
+		case c == '<' && s.peekByte(1) == '<' && isHeredocStart(s.peekByte(2), s.peekByte(3)):
+			s.lexHeredoc()
+		case isIdentStart(c):
+			name := s.lexIdent()
+			if s.peekByte(0) == ':' && s.peekByte(1) != ':' {
+				s.pos++
+				s.emit(tokLabel, name)
+			} else {
+				s.emit(tokIdent, name)
+			}
+		case c >= '0' && c <= '9':
+			start := s.pos
+			for s.pos < len(s.src) && (isDigit(s.src[s.pos]) || s.src[s.pos] == '.' || s.src[s.pos] == '_') {
+				s.pos++
+			}
+			s.emit(tokNumber, string(s.src[start:s.pos]))
+		case c == '=' && s.peekByte(1) == '>':
+			s.emit(tokPunct, "=>")
+			s.pos += 2
+		case strings.IndexByte(",()[]{}|=", c) > -1:
+			s.emit(tokPunct, string(c))
+			s.pos++
+		default:
+			s.emit(tokOther, string(c))
+			s.pos++
+		}
+	}
+	s.emit(tokNewline, "\n")
+	s.emit(tokEOF, "")
+}
+
+func (s *rbLexer) skipEmbeddedDoc() {
+	for s.pos < len(s.src) {
+		end := strings.IndexByte(string(s.src[s.pos:]), '\n')
+		line := ""
+		if end < 0 {
+			line = string(s.src[s.pos:])
+			s.pos = len(s.src)
+		} else {
+			line = string(s.src[s.pos : s.pos+end])
+			s.pos += end + 1
+			s.line++
+		}
+		if strings.HasPrefix(line, "=end") {
+			return
+		}
+	}
+}
+
+func (s *rbLexer) lexIdent() string {
+	start := s.pos
+	for s.pos < len(s.src) && isIdentChar(s.src[s.pos]) {
+		s.pos++
+	}
+	if c := s.peekByte(0); (c == '!' || c == '?') && s.peekByte(1) != '=' {
+		s.pos++
+	}
+	return string(s.src[start:s.pos])
+}
+
+func (s *rbLexer) lexString(quote byte) {
+	s.pos++
+	var buffer strings.Builder
+	tok := s.emit(tokString, "")
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		if c == quote {
+			s.pos++
+			tok.val = buffer.String()
+			return
+		}
+		if c == '\n' {
+			s.line++
+		}
+		if c == '\\' && s.pos+1 < len(s.src) {
+			n := s.src[s.pos+1]
+			s.pos += 2
+			if quote == '\'' {
+				if n != '\\' && n != '\'' {
+					buffer.WriteByte('\\')
+				}
+				buffer.WriteByte(n)
+				continue
+			}
+			switch n {
+			case 'n':
+				buffer.WriteByte('\n')
+			case 't':
+				buffer.WriteByte('\t')
+			default:
+				buffer.WriteByte(n)
+			}
+			continue
+		}
+		if quote == '"' && c == '#' && s.peekByte(1) == '{' {
+			tok.interp = true
+		}
+		buffer.WriteByte(c)
+		s.pos++
+	}
+	tok.kind = tokOther
+}
+
+func (s *rbLexer) lexWords() {
+	s.pos += 2
+	open := s.src[s.pos]
+	closer := map[byte]byte{'[': ']', '(': ')', '{': '}', '<': '>'}[open]
+	if closer == 0 {
+		closer = open
+	}
+	s.pos++
+	start := s.pos
+	for s.pos < len(s.src) && s.src[s.pos] != closer {
+		if s.src[s.pos] == '\n' {
+			s.line++
+		}
+		s.pos++
+	}
+	tok := s.emit(tokWords, "")
+	tok.words = strings.Fields(string(s.src[start:s.pos]))
+	if s.pos < len(s.src) {
+		s.pos++
+	}
+}
+
+// lexHeredoc reads <<ID, <<-ID and <<~ID strings. The body starts on the
+// next line, after the bodies of heredocs already opened on this line.
+func (s *rbLexer) lexHeredoc() {
+	s.pos += 2
+	indented, squiggly := false, false
+	switch s.peekByte(0) {
+	case '-':
+		indented = true
+		s.pos++
+	case '~':
+		indented, squiggly = true, true
+		s.pos++
+	}
+	quote := byte(0)
+	if c := s.peekByte(0); c == '\'' || c == '"' {
+		quote = c
+		s.pos++
+	}
+	id := s.lexIdent()
+	if quote != 0 && s.peekByte(0) == quote {
+		s.pos++
+	}
+	tok := s.emit(tokString, "")
+	start := s.heredocEnd
+	if start == 0 {
+		nl := strings.IndexByte(string(s.src[s.pos:]), '\n')
+		if nl < 0 {
+			tok.kind = tokOther
+			return
+		}
+		start = s.pos + nl + 1
+	}
+	lines := make([]string, 0, 5)
+	pos, count := start, 0
+	for pos < len(s.src) {
+		end := strings.IndexByte(string(s.src[pos:]), '\n')
+		line := ""
+		if end < 0 {
+			line, pos = string(s.src[pos:]), len(s.src)
+		} else {
+			line, pos = string(s.src[pos:pos+end]), pos+end+1
+		}
+		count++
+		term := strings.TrimRight(line, "\r")
+		if indented {
+			term = strings.TrimSpace(term)
+		}
+		if term == id {
+			s.heredocEnd, s.heredocLines = pos, s.heredocLines+count
+			if squiggly {
+				lines = dedentLines(lines)
+			}
+			tok.val = strings.Join(lines, "")
+			tok.interp = quote != '\'' && strings.Contains(tok.val, "#{")
+			return
+		}
+		lines = append(lines, line+"\n")
+	}
+	tok.kind = tokOther
+}
+
+func isHeredocStart(c, n byte) bool {
+	if c == '-' || c == '~' {
+		c = n
+	}
+	return c == '"' || c == '\'' || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+// dedentLines removes the indentation shared by the non blank lines.
+func dedentLines(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent <= 0 {
+		return lines
+	}
+	res := make([]string, len(lines))
+	for idx, line := range lines {
+		if len(line) >= indent && strings.TrimSpace(line[:indent]) == "" {
+			res[idx] = line[indent:]
+		} else {
+			res[idx] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return res
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isWordsOpen(c byte) bool {
+	return strings.IndexByte("[({<|!/", c) > -1
+}
+
+// ** Podfile Parser **
+type rbCall struct {
+	name   string
+	args   []interface{}
+	opts   map[interface{}]interface{}
+	line   int
+	block  bool
+	params []string
+}
+
+type podfileParser struct {
+	filePath string
+	src      []byte
+	tokens   []*rbToken
+	pos      int
+	result   *p_podfile
+
+	// valueHook evaluates the tokens parseValue does not know, it returns
+	// false to leave t to parseValue
+	valueHook func(t *rbToken) (interface{}, bool, error)
+
+	// Line ranges used to split the source into Header, body and Footer
+	bodyStart   int
+	bodyEnd     int
+	interleaved [][2]int
+
+	// Comments of the source by line and the ones of the statement being
+	// evaluated
+	comments  map[int]string
+	lines     [][]byte
+	statement *podfileComments
+}
+
+// podfileComments are the comment lines above a statement and the comment at
+// the end of its line.
+type podfileComments struct {
+	above  []string
+	inline string
+}
+
+// Hooks and settings that do not affect which pods are installed.
+var podfileIgnoredCalls = map[string]bool{
+	"workspace":                   true,
+	"project":                     true,
+	"xcodeproj":                   true,
+	"install!":                    true,
+	"inhibit_all_warnings!":       true,
+	"use_modular_headers!":        true,
+	"ensure_bundler!":             true,
+	"plugin":                      true,
+	"generate_bridge_support!":    true,
+	"set_arc_compatibility_flag!": true,
+	"supports_swift_versions":     true,
+	"link_with":                   true,
+}
+
+var podfileHookCalls = map[string]bool{
+	"pre_install":    true,
+	"post_install":   true,
+	"pre_integrate":  true,
+	"post_integrate": true,
+}
+
+func (s *podfileParser) unsupported(line int, reason string) error {
+	return &PodfileUnsupportedError{FilePath: s.filePath, Line: line, Reason: reason}
+}
+
+func (s *podfileParser) peek() *rbToken {
+	return s.tokens[s.pos]
+}
+
+func (s *podfileParser) next() *rbToken {
+	t := s.tokens[s.pos]
+	if t.kind != tokEOF {
+		s.pos++
+	}
+	return t
+}
+
+func (s *podfileParser) skipNewlines() {
+	for s.peek().kind == tokNewline {
+		s.pos++
+	}
+}
+
+func (s *podfileParser) parse() error {
+	root := &p_target_definition{Name: "Pods", Abstract: true}
+	s.result = &p_podfile{Target_definitions: []*p_target_definition{root}}
+	if err := s.parseBody(root, false); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseBody evaluates the statements of def. Inside a target block the
+// statements the model does not hold are kept as source text.
+func (s *podfileParser) parseBody(def *p_target_definition, inBlock bool) error {
+	prev := 0
+	if inBlock {
+		prev = s.tokens[s.pos-1].line
+	}
+	for {
+		s.skipNewlines()
+		t := s.peek()
+		if t.kind == tokEOF {
+			if inBlock {
+				return s.unsupported(t.line, "缺少end")
+			}
+			return nil
+		}
+		if t.kind == tokIdent && t.val == "end" {
+			if !inBlock {
+				return s.unsupported(t.line, "多余的end")
+			}
+			def.endComments = s.commentsBetween(prev, t.line)
+			s.next()
+			return nil
+		}
+		if t.kind != tokIdent {
+			return s.unsupported(t.line, "无法识别的语句")
+		}
+		// Comments above the first statement of the body stay in the Header
+		var above []string
+		if inBlock || s.bodyStart > 0 {
+			above = s.commentsBetween(prev, t.line)
+		}
+		call, err := s.parseCall()
+		if err != nil {
+			return err
+		}
+		s.statement = &podfileComments{above: above, inline: s.inlineComment(s.tokens[s.pos-1].line)}
+		if err := s.evalCall(def, call); err != nil {
+			return err
+		}
+		last := s.tokens[s.pos-1].line
+		if !inBlock {
+			start := t.line
+			if len(above) > 0 {
+				for start = prev + 1; !s.isCommentLine(start); start++ {
+				}
+			}
+			s.markStatement(call.name, start, last)
+		} else if text := s.rawStatement(call.name, above, t.line, last); text != "" {
+			def.statements = append(def.statements, text)
+		}
+		prev = last
+	}
+}
+
+// rawStatement returns the source of a statement of a target block that
+// evalCall does not store, with the comments above it. Only the comments are
+// returned for the settings written from the model.
+func (s *podfileParser) rawStatement(name string, above []string, startLine, endLine int) string {
+	if name == "pod" || name == "target" || name == "abstract_target" {
+		return ""
+	}
+	lines := append([]string(nil), above...)
+	if podfileIgnoredCalls[name] || podfileHookCalls[name] || name == "source" {
+		source := make([]string, 0, endLine-startLine+1)
+		for line := startLine; line <= endLine; line++ {
+			source = append(source, strings.TrimRight(s.sourceLine(line), " \t\r"))
+		}
+		lines = append(lines, dedentLines(source)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (s *podfileParser) sourceLine(line int) string {
+	if s.lines == nil {
+		s.lines = bytes.Split(s.src, []byte("\n"))
+	}
+	if line < 1 || line > len(s.lines) {
+		return ""
+	}
+	return string(s.lines[line-1])
+}
+
+func (s *podfileParser) isCommentLine(line int) bool {
+	return strings.HasPrefix(strings.TrimSpace(s.sourceLine(line)), "#")
+}
+
+// commentsBetween returns the comment lines after line from and before line
+// to.
+func (s *podfileParser) commentsBetween(from, to int) []string {
+	var res []string
+	for line := from + 1; line < to; line++ {
+		if c, ok := s.comments[line]; ok && s.isCommentLine(line) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// inlineComment returns the comment following the code of line.
+func (s *podfileParser) inlineComment(line int) string {
+	if c, ok := s.comments[line]; ok && !s.isCommentLine(line) {
+		return c
+	}
+	return ""
+}
+
+func (s *podfileParser) markStatement(name string, startLine, endLine int) {
+	if name == "pod" || name == "target" || name == "abstract_target" {
+		if s.bodyStart == 0 {
+			s.bodyStart = startLine
+		}
+		s.bodyEnd = endLine
+		return
+	}
+	if s.bodyStart > 0 {
+		s.interleaved = append(s.interleaved, [2]int{startLine, endLine})
+	}
+}
+
+// takeInterleaved returns the source of the top level statements found since
+// the previous target, which are then written above the next one.
+func (s *podfileParser) takeInterleaved() []string {
+	var res []string
+	for _, r := range s.interleaved {
+		lines := make([]string, 0, r[1]-r[0]+1)
+		for line := r[0]; line <= r[1]; line++ {
+			lines = append(lines, strings.TrimRight(s.sourceLine(line), " \t\r"))
+		}
+		res = append(res, strings.Join(lines, "\n"))
+	}
+	s.interleaved = nil
+	return res
+}
+
+// headerAndFooter returns the source before the first pod or target
+// statement and the source after the last one. Top level statements found
+// after the last target but before a pod are moved to the footer.
+func (s *podfileParser) headerAndFooter() ([]byte, []byte) {
+	lines := bytes.SplitAfter(s.src, []byte("\n"))
+	if s.bodyStart == 0 {
+		return s.src, nil
+	}
+	joinLines := func(from, to int) []byte {
+		var buffer bytes.Buffer
+		for i := from; i <= to && i <= len(lines); i++ {
+			buffer.Write(lines[i-1])
+		}
+		return buffer.Bytes()
+	}
+	header := joinLines(1, s.bodyStart-1)
+	var footer bytes.Buffer
+	for _, r := range s.interleaved {
+		if r[1] < s.bodyEnd {
+			footer.WriteString("\n")
+			footer.Write(joinLines(r[0], r[1]))
+		}
+	}
+	footer.Write(joinLines(s.bodyEnd+1, len(lines)))
+	return header, footer.Bytes()
+}
+
+func (s *podfileParser) parseCall() (*rbCall, error) {
+	t := s.next()
+	call := &rbCall{name: t.val, line: t.line}
+	paren := false
+	if p := s.peek(); p.kind == tokPunct && p.val == "(" && p.line == t.line {
+		s.next()
+		paren = true
+	}
+	for {
+		p := s.peek()
+		if paren && p.kind == tokPunct && p.val == ")" {
+			s.next()
+			break
+		}
+		if !paren && (p.kind == tokNewline || p.kind == tokEOF || (p.kind == tokIdent && p.val == "do")) {
+			break
+		}
+		if len(call.args) > 0 || call.opts != nil {
+			if p.kind != tokPunct || p.val != "," {
+				return nil, s.unsupported(p.line, "参数之间缺少逗号")
+			}
+			s.next()
+			if paren {
+				s.skipNewlines()
+			} else if s.peek().kind == tokNewline {
+				s.skipNewlines()
+			}
+		}
+		if err := s.parseArg(call); err != nil {
+			return nil, err
+		}
+	}
+	if p := s.peek(); p.kind == tokIdent && p.val == "do" {
+		s.next()
+		if p := s.peek(); p.kind == tokPunct && p.val == "|" {
+			s.next()
+			for p := s.next(); !(p.kind == tokPunct && p.val == "|"); p = s.next() {
+				if p.kind == tokEOF || p.kind == tokNewline {
+					return nil, s.unsupported(p.line, "无法识别的块参数")
+				}
+				if p.kind == tokIdent {
+					call.params = append(call.params, p.val)
+				}
+			}
+		}
+		call.block = true
+	}
+	return call, nil
+}
+
+func (s *podfileParser) parseArg(call *rbCall) error {
+	p := s.peek()
+	if p.kind == tokLabel {
+		s.next()
+		v, err := s.parseValue()
+		if err != nil {
+			return err
+		}
+		if call.opts == nil {
+			call.opts = make(map[interface{}]interface{})
+		}
+		call.opts[":"+p.val] = v
+		return nil
+	}
+	v, err := s.parseValue()
+	if err != nil {
+		return err
+	}
+	if a := s.peek(); a.kind == tokPunct && a.val == "=>" {
+		s.next()
+		val, err := s.parseValue()
+		if err != nil {
+			return err
+		}
+		if call.opts == nil {
+			call.opts = make(map[interface{}]interface{})
+		}
+		call.opts[v] = val
+		return nil
+	}
+	if call.opts != nil {
+		return s.unsupported(p.line, "位置参数出现在哈希参数之后")
+	}
+	call.args = append(call.args, v)
+	return nil
+}
+
+func (s *podfileParser) parseValue() (interface{}, error) {
+	t := s.next()
+	if s.valueHook != nil {
+		if v, ok, err := s.valueHook(t); ok || err != nil {
+			return v, err
+		}
+	}
+	switch t.kind {
+	case tokString:
+		if t.interp {
+			return nil, s.unsupported(t.line, "不支持字符串插值")
+		}
+		return t.val, nil
+	case tokSymbol:
+		return ":" + t.val, nil
+	case tokNumber:
+		return t.val, nil
+	case tokWords:
+		res := make([]interface{}, 0, len(t.words))
+		for _, w := range t.words {
+			res = append(res, w)
+		}
+		return res, nil
+	case tokIdent:
+		switch t.val {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "nil":
+			return nil, nil
+		}
+	case tokPunct:
+		switch t.val {
+		case "[":
+			return s.parseArray()
+		case "{":
+			return s.parseHash()
+		}
+	}
+	return nil, s.unsupported(t.line, "无法求值的表达式 "+t.val)
+}
+
+func (s *podfileParser) parseArray() (interface{}, error) {
+	res := make([]interface{}, 0, 2)
+	for {
+		s.skipNewlines()
+		if p := s.peek(); p.kind == tokPunct && p.val == "]" {
+			s.next()
+			return res, nil
+		}
+		v, err := s.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+		s.skipNewlines()
+		p := s.next()
+		if p.kind == tokPunct && p.val == "]" {
+			return res, nil
+		}
+		if p.kind != tokPunct || p.val != "," {
+			return nil, s.unsupported(p.line, "无法解析数组")
+		}
+	}
+}
+
+func (s *podfileParser) parseHash() (interface{}, error) {
+	res := make(map[interface{}]interface{})
+	for {
+		s.skipNewlines()
+		p := s.peek()
+		if p.kind == tokPunct && p.val == "}" {
+			s.next()
+			return res, nil
+		}
+		var key interface{}
+		if p.kind == tokLabel {
+			s.next()
+			key = ":" + p.val
+		} else {
+			k, err := s.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			if a := s.next(); a.kind != tokPunct || a.val != "=>" {
+				return nil, s.unsupported(a.line, "无法解析哈希")
+			}
+			key = k
+		}
+		v, err := s.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		res[key] = v
+		s.skipNewlines()
+		n := s.next()
+		if n.kind == tokPunct && n.val == "}" {
+			return res, nil
+		}
+		if n.kind != tokPunct || n.val != "," {
+			return nil, s.unsupported(n.line, "无法解析哈希")
+		}
+	}
+}
+
+// skipBlock consumes a do ... end block without evaluating it.
+func (s *podfileParser) skipBlock(line int) error {
+	depth := 1
+	statementStart := true
+	for {
+		t := s.next()
+		switch t.kind {
+		case tokEOF:
+			return s.unsupported(line, "块缺少end")
+		case tokNewline:
+			statementStart = true
+			continue
+		case tokIdent:
+			switch t.val {
+			case "do", "def", "class", "module", "begin", "case", "for":
+				depth++
+			case "if", "unless", "while", "until":
+				if statementStart {
+					depth++
+				}
+			case "end":
+				depth--
+				if depth == 0 {
+					return nil
+				}
+			}
+		}
+		statementStart = t.kind == tokPunct && t.val == "="
+	}
+}
+
+func (s *podfileParser) evalCall(def *p_target_definition, call *rbCall) error {
+	if podfileHookCalls[call.name] {
+		if !call.block {
+			return s.unsupported(call.line, call.name+" 缺少块")
+		}
+		return s.skipBlock(call.line)
+	}
+	if call.block && call.name != "target" && call.name != "abstract_target" {
+		return s.unsupported(call.line, call.name+" 不支持块")
+	}
+	switch call.name {
+	case "source":
+		src, ok := stringArg(call, 0)
+		if !ok {
+			return s.unsupported(call.line, "source 参数错误")
+		}
+		s.result.Sources = append(s.result.Sources, src)
+	case "platform":
+		name, ok := stringArg(call, 0)
+		if !ok {
+			return s.unsupported(call.line, "platform 参数错误")
+		}
+		name = strings.TrimPrefix(name, ":")
+		if v, ok := stringArg(call, 1); ok {
+			def.Platform = map[interface{}]interface{}{name: v}
+		} else {
+			def.Platform = name
+		}
+	case "use_frameworks!":
+		if len(call.args) > 0 {
+			if b, ok := call.args[0].(bool); ok {
+				def.Uses_frameworks = b
+				break
+			}
+		}
+		if call.opts != nil {
+			def.Uses_frameworks = trimOptionKeys(call.opts)
+		} else {
+			def.Uses_frameworks = true
+		}
+	case "inherit!":
+		mode, ok := stringArg(call, 0)
+		if !ok {
+			return s.unsupported(call.line, "inherit! 参数错误")
+		}
+		def.Inheritance = strings.TrimPrefix(mode, ":")
+	case "abstract!":
+		def.Abstract = len(call.args) == 0 || call.args[0] != false
+	case "target", "abstract_target":
+		name, ok := stringArg(call, 0)
+		if !ok || !call.block {
+			return s.unsupported(call.line, call.name+" 参数错误")
+		}
+		child := &p_target_definition{Name: strings.TrimPrefix(name, ":"), Abstract: call.name == "abstract_target", comments: s.statement}
+		child.leadingStatements = s.takeInterleaved()
+		def.Children = append(def.Children, child)
+		return s.parseBody(child, true)
+	case "pod":
+		dep, err := s.evalPod(call)
+		if err != nil {
+			return err
+		}
+		def.Dependencies = append(def.Dependencies, dep)
+		def.dependencyComments = append(def.dependencyComments, s.statement)
+	default:
+		if !podfileIgnoredCalls[call.name] {
+			return s.unsupported(call.line, "不支持的方法 "+call.name)
+		}
+	}
+	return nil
+}
+
+func (s *podfileParser) evalPod(call *rbCall) (interface{}, error) {
+	name, ok := stringArg(call, 0)
+	if !ok || strings.HasPrefix(name, ":") {
+		return nil, s.unsupported(call.line, "pod 参数错误")
+	}
+	requirements := make([]interface{}, 0, len(call.args))
+	for i := 1; i < len(call.args); i++ {
+		r, ok := stringArg(call, i)
+		if !ok {
+			return nil, s.unsupported(call.line, "pod 版本参数错误")
+		}
+		requirements = append(requirements, r)
+	}
+	if call.opts != nil {
+		requirements = append(requirements, call.opts)
+	}
+	if len(requirements) == 0 {
+		return name, nil
+	}
+	return map[interface{}]interface{}{name: requirements}, nil
+}
+
+func stringArg(call *rbCall, idx int) (string, bool) {
+	if idx >= len(call.args) {
+		return "", false
+	}
+	v, ok := call.args[idx].(string)
+	return v, ok
+}
+
+func trimOptionKeys(m map[interface{}]interface{}) map[interface{}]interface{} {
+	res := make(map[interface{}]interface{}, len(m))
+	for k, v := range m {
+		if ks, ok := k.(string); ok {
+			k = strings.TrimPrefix(ks, ":")
+		}
+		res[k] = v
+	}
+	return res
+}
+
+// ** PodfileUnsupportedError Impl **
+func (s *PodfileUnsupportedError) Error() string {
+	return "无法解析Podfile: " + s.FilePath + ":" + strconv.Itoa(s.Line) + " " + s.Reason
+}
+
+// ** Func Public **
+
+// ParsePodfile parses the declarative subset of the Podfile DSL without
+// running CocoaPods. It returns a *PodfileUnsupportedError when the file
+// needs a Ruby interpreter to be evaluated.
+func ParsePodfile(filePath string) (*Podfile, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return NewPodfileWithBytes(filePath, b)
+}
+
+func NewPodfileWithBytes(filePath string, b []byte) (*Podfile, error) {
+	parser, err := parsePodfileDefinition(filePath, b)
+	if err != nil {
+		return nil, err
+	}
+	aPodfile := newPodfileWithDefinition(filePath, parser.result)
+	aPodfile.Header, aPodfile.Footer = parser.headerAndFooter()
+	return aPodfile, nil
+}
+
+// ** Func Private **
+func parsePodfileDefinition(filePath string, b []byte) (*podfileParser, error) {
+	lexer := rbLex(b)
+	parser := &podfileParser{filePath: filePath, src: b, tokens: lexer.tokens, comments: lexer.comments}
+	if err := parser.parse(); err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
@@ -0,0 +1,250 @@
+package pod
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testModuleVersions maps the modules of aTarget to their requirement.
+func testModuleVersions(aTarget *PodfileTarget) map[string]string {
+	res := make(map[string]string, len(aTarget.Modules))
+	for _, aModule := range aTarget.Modules {
+		res[aModule.N] = aModule.V
+	}
+	return res
+}
+
+func TestNewPodfileWithBytes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		target string
+		want   map[string]string
+		check  func(t *testing.T, aPodfile *Podfile)
+	}{
+		{
+			name: "requirements",
+			source: `source 'https://cdn.cocoapods.org/'
+target 'App' do
+  platform :ios, '11.0'
+  use_frameworks!
+  pod 'A'
+  pod 'B', '~> 1.0'
+  pod 'D', :path => '../D'
+end
+`,
+			target: "App",
+			want:   map[string]string{"A": "", "B": "~> 1.0", "D": ""},
+			check: func(t *testing.T, aPodfile *Podfile) {
+				if !reflect.DeepEqual(aPodfile.Sources, []string{"https://cdn.cocoapods.org/"}) {
+					t.Errorf("Sources = %v", aPodfile.Sources)
+				}
+				aTarget := aPodfile.TargetWithName("App")
+				if aTarget.Platform != "ios" || aTarget.PlatformVersion != "11.0" || !aTarget.UseFrameworks {
+					t.Errorf("App = %s %s %v, want ios 11.0 with frameworks", aTarget.Platform, aTarget.PlatformVersion, aTarget.UseFrameworks)
+				}
+				if m := aTarget.ModuleWithName("D"); !m.IsLocal() || m.SpecPath != "../D" {
+					t.Errorf("D = %s, want a local module", m.SpecPath)
+				}
+			},
+		},
+		{
+			name: "pod options",
+			source: `target 'App' do
+  pod 'C', '>= 1.0', '< 2.0'
+  pod 'E', :git => 'https://example.com/E.git', :tag => '1.0'
+  pod 'F', '~> 2.0', :subspecs => ['Core', 'UI']
+  pod 'P', :podspec => 'Specs/P.podspec'
+  pod 'R', :configurations => ['Debug'], :modular_headers => true, :inhibit_warnings => false
+  pod 'X', :source => 'https://example.com/specs.git', :testspecs => ['Tests'], :project_name => 'X'
+end
+`,
+			target: "App",
+			want: map[string]string{
+				"C": ">= 1.0", "E": "", "F/Core": "~> 2.0", "F/UI": "~> 2.0", "P": "", "R": "", "X": "",
+			},
+			check: func(t *testing.T, aPodfile *Podfile) {
+				aTarget := aPodfile.TargetWithName("App")
+				if m := aTarget.ModuleWithName("C"); !reflect.DeepEqual(m.Requirements, []string{">= 1.0", "< 2.0"}) {
+					t.Errorf("C requirements = %v", m.Requirements)
+				}
+				if m := aTarget.ModuleWithName("E"); m.Type != "git" || m.Options.Git != "https://example.com/E.git" || m.Options.Tag != "1.0" {
+					t.Errorf("E = %s %+v, want a git module with tag", m.Type, m.Options)
+				}
+				if m := aTarget.ModuleWithName("P"); m.Type != "podspec" || m.SpecPath != "Specs/P.podspec" {
+					t.Errorf("P = %s %s, want a podspec module", m.Type, m.SpecPath)
+				}
+				m := aTarget.ModuleWithName("R")
+				if !reflect.DeepEqual(m.Options.Configurations, []string{"Debug"}) || m.Options.ModularHeaders == nil || !*m.Options.ModularHeaders ||
+					m.Options.InhibitWarnings == nil || *m.Options.InhibitWarnings {
+					t.Errorf("R options = %+v", m.Options)
+				}
+				m = aTarget.ModuleWithName("X")
+				if m.Options.Source != "https://example.com/specs.git" || !reflect.DeepEqual(m.Options.Testspecs, []string{"Tests"}) || m.Options.Other["project_name"] != "X" {
+					t.Errorf("X options = %+v", m.Options)
+				}
+			},
+		},
+		{
+			name: "nested and abstract targets",
+			source: `abstract_target 'Shared' do
+  pod 'A'
+  target 'App' do
+    pod 'B'
+    target 'AppTests' do
+      inherit! :search_paths
+      pod 'C'
+    end
+  end
+end
+`,
+			target: "AppTests",
+			want:   map[string]string{"C": ""},
+			check: func(t *testing.T, aPodfile *Podfile) {
+				names := make([]string, 0, len(aPodfile.Targets))
+				for _, aTarget := range aPodfile.Targets {
+					names = append(names, aTarget.Name)
+				}
+				if got := strings.Join(names, ","); got != "Pods,Shared,App,AppTests" {
+					t.Errorf("Targets = %s, want Pods,Shared,App,AppTests", got)
+				}
+				if aPodfile.Root != aPodfile.Targets[0] || !aPodfile.Root.Abstract {
+					t.Error("Root is not the abstract Targets[0]")
+				}
+				if aTarget := aPodfile.TargetWithName("Shared"); !aTarget.Abstract || aTarget.Parent != aPodfile.Root {
+					t.Errorf("Shared = %+v, want an abstract child of Root", aTarget)
+				}
+				if aTarget := aPodfile.TargetWithName("AppTests"); aTarget.Inheritance != PodfileInheritanceSearchPaths || aTarget.Parent.Name != "App" {
+					t.Errorf("AppTests = %+v, want search_paths under App", aTarget)
+				}
+			},
+		},
+		{
+			name: "heredocs and ignored statements",
+			source: `install! 'cocoapods', :deterministic_uuids => false
+target 'App' do
+  project 'App.xcodeproj'
+  pod 'A', '1.0'
+end
+post_install do |installer|
+  File.write('notes.txt', <<-EOS)
+    pod 'Ignored'
+  EOS
+end
+`,
+			target: "App",
+			want:   map[string]string{"A": "1.0"},
+		},
+		{
+			name:   "pods outside any target",
+			source: "pod 'A', '~> 1.0'\ntarget 'App' do\n  pod 'B'\nend\n",
+			target: "Pods",
+			want:   map[string]string{"A": "~> 1.0"},
+		},
+		{
+			name:   "pods outside any target by their old name",
+			source: "pod 'A', '~> 1.0'\ntarget 'App' do\n  pod 'B'\nend\n",
+			target: PodfileRootTargetName,
+			want:   map[string]string{"A": "~> 1.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aPodfile, err := NewPodfileWithBytes("Podfile", []byte(tt.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			aTarget := aPodfile.TargetWithName(tt.target)
+			if aTarget == nil {
+				t.Fatalf("no target %s", tt.target)
+			}
+			if got := testModuleVersions(aTarget); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("modules of %s = %v, want %v", tt.target, got, tt.want)
+			}
+			if tt.check != nil {
+				tt.check(t, aPodfile)
+			}
+		})
+	}
+}
+
+func TestPodfileTargetEffectiveModules(t *testing.T) {
+	source := `platform :ios, '11.0'
+pod 'Root'
+target 'App' do
+  pod 'A', '~> 1.0'
+  target 'AppTests' do
+    pod 'A', '~> 1.2'
+    pod 'T'
+  end
+  target 'AppSearch' do
+    inherit! :search_paths
+    pod 'S'
+  end
+  target 'AppMac' do
+    platform :osx, '10.12'
+    pod 'M'
+  end
+end
+`
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		target    string
+		want      string
+		platform  string
+		exclusive bool
+	}{
+		{target: "Pods", want: "Root", platform: "ios", exclusive: true},
+		{target: "App", want: "A ~> 1.0,Root", platform: "ios"},
+		{target: "AppTests", want: "A ~> 1.2,T,Root", platform: "ios"},
+		{target: "AppSearch", want: "S", platform: "ios", exclusive: true},
+		{target: "AppMac", want: "M", platform: "osx", exclusive: true},
+	}
+	for _, tt := range tests {
+		aTarget := aPodfile.TargetWithName(tt.target)
+		items := make([]string, 0, 4)
+		for _, aModule := range aTarget.EffectiveModules() {
+			items = append(items, strings.TrimSpace(aModule.N+" "+aModule.V))
+		}
+		if got := strings.Join(items, ","); got != tt.want {
+			t.Errorf("%s: EffectiveModules() = %s, want %s", tt.target, got, tt.want)
+		}
+		if platform, _ := aTarget.EffectivePlatform(); platform != tt.platform {
+			t.Errorf("%s: EffectivePlatform() = %s, want %s", tt.target, platform, tt.platform)
+		}
+		if aTarget.IsExclusive() != tt.exclusive {
+			t.Errorf("%s: IsExclusive() = %v, want %v", tt.target, aTarget.IsExclusive(), tt.exclusive)
+		}
+	}
+}
+
+func TestNewPodfileWithBytesUnsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   int
+	}{
+		{name: "method definition", source: "target 'App' do\n  pod 'A'\nend\n\ndef shared\n  pod 'B'\nend\n", line: 5},
+		{name: "assignment", source: "v = '1.0'\n", line: 1},
+		{name: "string interpolation", source: "target 'App' do\n  pod 'A', \"#{ENV['V']}\"\nend\n", line: 2},
+		{name: "missing end", source: "target 'App' do\n  pod 'A'\n", line: 3},
+		{name: "extra end", source: "pod 'A'\nend\n", line: 2},
+		{name: "block on pod", source: "pod 'A' do\nend\n", line: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPodfileWithBytes("Podfile", []byte(tt.source))
+			uErr, ok := err.(*PodfileUnsupportedError)
+			if !ok {
+				t.Fatalf("error = %v, want a *PodfileUnsupportedError", err)
+			}
+			if uErr.Line != tt.line {
+				t.Errorf("error line = %d (%s), want %d", uErr.Line, uErr.Reason, tt.line)
+			}
+		})
+	}
+}
@@ -1,37 +1,129 @@
 package pod
 
+const (
+	PodfileInheritanceComplete    = "complete"
+	PodfileInheritanceNone        = "none"
+	PodfileInheritanceSearchPaths = "search_paths"
+)
+
+// PodfileRootTargetName is the name the pods declared outside any target
+// block had before the target tree, TargetWithName still returns Root for it
+const PodfileRootTargetName = "*"
+
+// Podfile keeps the target definitions as a tree under Root, Targets lists
+// every definition of the tree in declaration order. Root is Targets[0], the
+// abstract target "Pods" CocoaPods puts around the file, it holds the pods
+// declared outside any target block. Runner runs the CocoaPods commands of
+// the Podfile, nil means DefaultCommandRunner.
 type Podfile struct {
 	FilePath string
 	Header   []byte
+	Sources  []string
+	Root     *PodfileTarget
 	Targets  []*PodfileTarget
 	Footer   []byte
+	Runner   CommandRunner
 }
 
+// PodfileTarget is a target definition. UseFrameworksOptions holds the
+// options of `use_frameworks!` such as linkage, without colons. Statements
+// keeps the source of the statements of the block the model does not hold,
+// like `project` or `inhibit_all_warnings!`, with their comments, it is
+// written back after the settings. LeadingStatements are the top level
+// statements found between the previous target and this one, such as a
+// hook, written back above it. Comments are the comment lines above the
+// target, LineComment ends its first line and EndComments precede its end.
 type PodfileTarget struct {
-	Name    string
-	Modules []*PodfileModule
+	Name                 string
+	Abstract             bool
+	Inheritance          string
+	Platform             string
+	PlatformVersion      string
+	UseFrameworks        bool
+	UseFrameworksOptions map[string]string
+	Modules              []*PodfileModule
+	Statements           []string
+	LeadingStatements    []string
+
+	Comments    []string
+	LineComment string
+	EndComments []string
+
+	Parent   *PodfileTarget
+	Children []*PodfileTarget
 }
 
+// PodfileModule is a `pod` line of a target. V is the first version
+// requirement and Requirements keeps all of them, a V that differs from
+// Requirements[0] replaces it. Type is the option that locates the spec
+// ("path", "podspec" or "git") and SpecPath is the local path for the "path"
+// and "podspec" types. Comments are the comment lines above the line and
+// LineComment ends it.
 type PodfileModule struct {
 	DependBase
-	Type     string
-	SpecPath string
-	Depends  []*DependBase
+	Requirements []string
+	Type         string
+	SpecPath     string
+	Options      *PodfileModuleOptions
+	Depends      []*DependBase
+
+	Comments    []string
+	LineComment string
+
+	// The `pod` line with :subspecs the module was expanded from
+	subspecsOf *PodfileModule
+}
+
+// PodfileModuleOptions holds the option hash of a `pod` line. Options with
+// no field here are kept in Other with the leading colon trimmed.
+type PodfileModuleOptions struct {
+	Path            string
+	Podspec         string
+	Git             string
+	Branch          string
+	Tag             string
+	Commit          string
+	Source          string
+	Subspecs        []string
+	Testspecs       []string
+	Configurations  []string
+	ModularHeaders  *bool
+	InhibitWarnings *bool
+	Other           map[string]interface{}
+}
+
+// PodfileUnsupportedError is returned by ParsePodfile when the Podfile
+// contains Ruby that the native parser can not evaluate.
+type PodfileUnsupportedError struct {
+	FilePath string
+	Line     int
+	Reason   string
 }
 
 // *** Private ***
 type p_podfile struct {
+	Sources            []string
 	Target_definitions []*p_target_definition
 }
 
 type p_target_definition struct {
-	Abstract     bool
-	Children     []*p_target
-	Dependencies []interface{}
-	Name         string
-}
+	Abstract        bool
+	Children        []*p_target_definition
+	Dependencies    []interface{}
+	Inheritance     string
+	Name            string
+	Platform        interface{}
+	Uses_frameworks interface{}
+
+	// Filled by `pod ipc podfile` from the options it moves out of `pod`
+	Configuration_pod_whitelist map[string][]string
+	Inhibit_warnings            map[string]interface{}
+	Use_modular_headers         map[string]interface{}
 
-type p_target struct {
-	Dependencies []interface{}
-	Name         string
+	// Filled by the native parser
+	comments           *podfileComments
+	dependencyComments []*podfileComments
+	statements         []string
+	leadingStatements  []string
+	endComments        []string
 }
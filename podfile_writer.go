@@ -0,0 +1,305 @@
+package pod
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const podfileIndent = "  "
+
+// ** Podfile Writer **
+
+// Bytes regenerates the Podfile from the target tree under Root. Header and
+// Footer are written back unchanged, so comments, sources and hooks such as
+// post_install survive a load-modify-save cycle, as do the Statements,
+// LeadingStatements and comments of the targets. When Header is empty the
+// sources are generated.
+func (s *Podfile) Bytes() []byte {
+	var buffer bytes.Buffer
+	if len(s.Header) > 0 {
+		buffer.Write(s.Header)
+	} else {
+		for _, source := range s.Sources {
+			if line := "source " + rubyString(source); !s.hasStatement(line) {
+				buffer.WriteString(line + "\n")
+			}
+		}
+		if buffer.Len() > 0 {
+			buffer.WriteString("\n")
+		}
+	}
+	if buffer.Len() > 0 && !bytes.HasSuffix(buffer.Bytes(), []byte("\n")) {
+		buffer.WriteString("\n")
+	}
+
+	if s.Root != nil {
+		if len(s.Header) == 0 {
+			s.Root.writeSettings(&buffer, "")
+		}
+		writeModules(&buffer, s.Root.Modules, "")
+		first := len(s.Root.Modules) == 0
+		for _, child := range s.Root.Children {
+			if !first {
+				buffer.WriteString("\n")
+			}
+			child.writeTo(&buffer, "")
+			first = false
+		}
+	}
+
+	if len(s.Footer) > 0 {
+		if !bytes.HasPrefix(s.Footer, []byte("\n")) {
+			buffer.WriteString("\n")
+		}
+		buffer.Write(s.Footer)
+	}
+	return buffer.Bytes()
+}
+
+// Save writes the regenerated Podfile to filePath, or to FilePath when
+// filePath is empty.
+func (s *Podfile) Save(filePath string) error {
+	if filePath == "" {
+		filePath = s.FilePath
+	}
+	return ioutil.WriteFile(filePath, s.Bytes(), 0644)
+}
+
+// hasStatement reports whether a target keeps line in its Statements, like a
+// source declared inside a target block.
+func (s *Podfile) hasStatement(line string) bool {
+	for _, aTarget := range s.Targets {
+		for _, statement := range aTarget.Statements {
+			for _, l := range strings.Split(statement, "\n") {
+				if strings.TrimSpace(l) == line {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (s *PodfileTarget) writeTo(buffer *bytes.Buffer, indent string) {
+	keyword := "target "
+	if s.Abstract {
+		keyword = "abstract_target "
+	}
+	for _, statement := range s.LeadingStatements {
+		writeStatement(buffer, statement, indent)
+		buffer.WriteString("\n")
+	}
+	writeComments(buffer, s.Comments, indent)
+	buffer.WriteString(withLineComment(indent+keyword+rubyString(s.Name)+" do", s.LineComment) + "\n")
+	inner := indent + podfileIndent
+	s.writeSettings(buffer, inner)
+	for _, statement := range s.Statements {
+		writeStatement(buffer, statement, inner)
+	}
+	writeModules(buffer, s.Modules, inner)
+	for idx, child := range s.Children {
+		if idx > 0 || len(s.Modules) > 0 {
+			buffer.WriteString("\n")
+		}
+		child.writeTo(buffer, inner)
+	}
+	writeComments(buffer, s.EndComments, inner)
+	buffer.WriteString(indent + "end\n")
+}
+
+func (s *PodfileTarget) writeSettings(buffer *bytes.Buffer, indent string) {
+	if s.Platform != "" {
+		buffer.WriteString(indent + "platform :" + s.Platform)
+		if s.PlatformVersion != "" {
+			buffer.WriteString(", " + rubyString(s.PlatformVersion))
+		}
+		buffer.WriteString("\n")
+	}
+	if s.UseFrameworks {
+		buffer.WriteString(indent + "use_frameworks!")
+		keys := make([]string, 0, len(s.UseFrameworksOptions))
+		for key := range s.UseFrameworksOptions {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for idx, key := range keys {
+			if idx > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(" :" + key + " => " + rubySymbol(s.UseFrameworksOptions[key]))
+		}
+		buffer.WriteString("\n")
+	}
+	if s.Inheritance != "" {
+		buffer.WriteString(indent + "inherit! :" + s.Inheritance + "\n")
+	}
+}
+
+func (s *PodfileModule) rubyDSL() string {
+	return s.rubyDSLWithSubspecs(s.N, nil)
+}
+
+// rubyDSLWithSubspecs writes the module as the `pod name` line declaring
+// subspecs, when subspecs is not empty.
+func (s *PodfileModule) rubyDSLWithSubspecs(name string, subspecs []string) string {
+	line := "pod " + rubyString(name)
+	if !s.IsLocal() {
+		for _, r := range s.VersionRequirements() {
+			line += ", " + rubyString(r)
+		}
+	}
+	opts := s.Options
+	if opts == nil {
+		if s.Type != "" && s.SpecPath != "" {
+			line += ", :" + strings.TrimPrefix(s.Type, ":") + " => " + rubyString(s.SpecPath)
+		}
+		if len(subspecs) > 0 {
+			line += ", :subspecs => " + rubyValue(subspecs)
+		}
+		return line
+	}
+	add := func(key string, val interface{}) {
+		line += ", :" + key + " => " + rubyValue(val)
+	}
+	for _, kv := range [][2]string{
+		{"path", opts.Path}, {"podspec", opts.Podspec}, {"git", opts.Git},
+		{"branch", opts.Branch}, {"tag", opts.Tag}, {"commit", opts.Commit}, {"source", opts.Source},
+	} {
+		if kv[1] != "" {
+			add(kv[0], kv[1])
+		}
+	}
+	if len(subspecs) > 0 {
+		add("subspecs", subspecs)
+	} else if len(opts.Subspecs) > 0 {
+		add("subspecs", opts.Subspecs)
+	}
+	if len(opts.Testspecs) > 0 {
+		add("testspecs", opts.Testspecs)
+	}
+	if len(opts.Configurations) > 0 {
+		add("configurations", opts.Configurations)
+	}
+	if opts.ModularHeaders != nil {
+		add("modular_headers", *opts.ModularHeaders)
+	}
+	if opts.InhibitWarnings != nil {
+		add("inhibit_warnings", *opts.InhibitWarnings)
+	}
+	keys := make([]string, 0, len(opts.Other))
+	for key := range opts.Other {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		add(key, opts.Other[key])
+	}
+	return line
+}
+
+// ** Func Private **
+
+// writeModules writes a `pod` line per module. The modules expanded from one
+// `:subspecs` line are written back as that line while they still follow
+// each other and agree on everything but the subspec.
+func writeModules(buffer *bytes.Buffer, modules []*PodfileModule, indent string) {
+	for idx := 0; idx < len(modules); idx++ {
+		aModule := modules[idx]
+		line := aModule.rubyDSL()
+		if origin := aModule.subspecsOf; origin != nil {
+			prefix := origin.N + "/"
+			subspecs := make([]string, 0, len(origin.Options.Subspecs))
+			for next := idx; next < len(modules); next++ {
+				m := modules[next]
+				if next > idx && (len(m.Comments) > 0 || m.LineComment != "") {
+					break
+				}
+				if m.subspecsOf != origin || !strings.HasPrefix(m.N, prefix) || m.rubyDSLWithSubspecs(origin.N, nil) != aModule.rubyDSLWithSubspecs(origin.N, nil) {
+					break
+				}
+				subspecs = append(subspecs, strings.TrimPrefix(m.N, prefix))
+			}
+			if len(subspecs) > 0 {
+				line = aModule.rubyDSLWithSubspecs(origin.N, subspecs)
+				idx += len(subspecs) - 1
+			}
+		}
+		writeComments(buffer, aModule.Comments, indent)
+		buffer.WriteString(withLineComment(indent+line, aModule.LineComment) + "\n")
+	}
+}
+
+// writeStatement writes the source of a statement, indenting its lines.
+func writeStatement(buffer *bytes.Buffer, statement, indent string) {
+	for _, line := range strings.Split(statement, "\n") {
+		if line != "" {
+			line = indent + line
+		}
+		buffer.WriteString(line + "\n")
+	}
+}
+
+func writeComments(buffer *bytes.Buffer, comments []string, indent string) {
+	for _, c := range comments {
+		buffer.WriteString(indent + c + "\n")
+	}
+}
+
+func withLineComment(line, comment string) string {
+	if comment == "" {
+		return line
+	}
+	return line + " " + comment
+}
+
+// rubySymbol writes v as a symbol when it is a plain name.
+func rubySymbol(v string) string {
+	if regRubySymbol.MatchString(v) {
+		return ":" + v
+	}
+	return rubyString(v)
+}
+
+func rubyValue(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		if strings.HasPrefix(x, ":") && len(x) > 1 {
+			return x
+		}
+		return rubyString(x)
+	case bool:
+		return strconv.FormatBool(x)
+	case nil:
+		return "nil"
+	case []string:
+		items := make([]string, 0, len(x))
+		for _, item := range x {
+			items = append(items, rubyString(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case []interface{}:
+		items := make([]string, 0, len(x))
+		for _, item := range x {
+			items = append(items, rubyValue(item))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[interface{}]interface{}:
+		items := make([]string, 0, len(x))
+		for k, val := range x {
+			items = append(items, rubyValue(k)+" => "+rubyValue(val))
+		}
+		sort.Strings(items)
+		return "{ " + strings.Join(items, ", ") + " }"
+	}
+	return rubyString(fmt.Sprint(v))
+}
+
+func rubyString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `\'`, -1)
+	return "'" + s + "'"
+}
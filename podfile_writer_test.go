@@ -0,0 +1,259 @@
+package pod
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPodfileBytesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{
+			name: "header and footer",
+			source: `# Podfile of App
+source 'https://cdn.cocoapods.org/'
+
+pod 'AFNetworking', '~> 3.0'
+
+target 'App' do
+  platform :ios, '11.0'
+  use_frameworks!
+  pod 'Masonry'
+  pod 'Local', :path => '../Local'
+end
+
+post_install do |installer|
+  puts 'done'
+end
+`,
+		},
+		{
+			name: "nested targets",
+			source: `abstract_target 'Shared' do
+  pod 'A'
+
+  target 'App' do
+    pod 'B'
+
+    target 'AppTests' do
+      inherit! :search_paths
+      pod 'C'
+    end
+  end
+end
+`,
+		},
+		{
+			name: "pod options",
+			source: `target 'App' do
+  pod 'E', :git => 'https://example.com/E.git', :tag => '1.0'
+  pod 'P', :podspec => 'Specs/P.podspec'
+  pod 'R', :configurations => ['Debug'], :modular_headers => true
+  pod 'X', '~> 1.0', :source => 'https://example.com/specs.git', :project_name => 'X'
+end
+`,
+		},
+		{
+			name:   "targets only",
+			source: "target 'App' do\n  pod 'Foo', '1.0'\nend\n\ntarget 'Other' do\n  pod 'Bar'\nend\n",
+		},
+		{
+			name: "ignored statements and comments",
+			source: `source 'https://cdn.cocoapods.org/'
+platform :ios, '11.0'
+
+# Networking
+pod 'AFNetworking', '~> 3.0' # pinned by the backend team
+
+target 'App' do
+  use_frameworks! :linkage => :static
+  project 'App.xcodeproj'
+  inhibit_all_warnings!
+  use_modular_headers!
+  # Layout
+  pod 'Masonry'
+
+  target 'AppTests' do # unit tests
+    inherit! :search_paths
+    pod 'Quick', '>= 1.0', '< 3.0'
+    # more to come
+  end
+end
+`,
+		},
+		{
+			name: "subspecs written on one line",
+			source: `target 'App' do
+  pod 'Foo', '~> 1.0', :subspecs => ['Core', 'Util']
+  pod 'Bar', :path => '../Bar'
+end
+`,
+		},
+		{
+			name: "statements with blocks inside a target",
+			source: `target 'App' do
+  platform :ios, '12.0'
+  source 'https://example.com/specs.git'
+  post_install do |installer|
+    installer.pods_project.targets.each do |target|
+      puts target.name
+    end
+  end
+  pod 'Foo'
+end
+`,
+		},
+		{
+			name: "statements between targets",
+			source: `platform :ios, '10.0'
+
+target 'App' do
+  pod 'Foo'
+end
+
+# Keep the hook here
+post_install do |installer|
+  puts 'done'
+end
+
+target 'Other' do
+  pod 'Bar'
+end
+
+install! 'cocoapods', :deterministic_uuids => false
+
+abstract_target 'Shared' do
+  pod 'Baz'
+end
+`,
+		},
+		{
+			name: "hooks and interleaved statements",
+			source: `platform :ios, '10.0'
+
+target 'App' do
+  pod 'Foo'
+end
+
+# Keep the hook
+post_install do |installer|
+  puts 'done'
+end
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aPodfile, err := NewPodfileWithBytes("Podfile", []byte(tt.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(aPodfile.Bytes()); got != tt.source {
+				t.Errorf("Bytes() =\n%s\nwant\n%s", got, tt.source)
+			}
+		})
+	}
+}
+
+func TestPodfileBytesAfterChanges(t *testing.T) {
+	source := "source 'https://cdn.cocoapods.org/'\n\ntarget 'App' do\n  pod 'Foo', '~> 1.0'\n  pod 'Bar'\nend\n"
+	tests := []struct {
+		name   string
+		change func(aTarget *PodfileTarget)
+		want   string
+	}{
+		{
+			name:   "version",
+			change: func(aTarget *PodfileTarget) { aTarget.SetModuleVersion("Foo", "1.2") },
+			want:   "  pod 'Foo', '1.2'\n  pod 'Bar'\n",
+		},
+		{
+			name:   "added module",
+			change: func(aTarget *PodfileTarget) { aTarget.AddModule("Baz", ">= 2.0") },
+			want:   "  pod 'Bar'\n  pod 'Baz', '>= 2.0'\nend\n",
+		},
+		{
+			name:   "removed module",
+			change: func(aTarget *PodfileTarget) { aTarget.RemoveModule("Bar") },
+			want:   "  pod 'Foo', '~> 1.0'\nend\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aPodfile, err := NewPodfileWithBytes("Podfile", []byte(source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(aPodfile.TargetWithName("App"))
+			got := string(aPodfile.Bytes())
+			if !strings.Contains(got, tt.want) || !strings.HasPrefix(got, "source 'https://cdn.cocoapods.org/'\n") {
+				t.Errorf("Bytes() =\n%s\nwant it to contain\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodfileBytesStatementsBetweenTargets(t *testing.T) {
+	source := "target 'App' do\n  pod 'Foo'\nend\n\n# hook\npost_install do |installer|\nend\n\ntarget 'Other' do\n  pod 'Bar'\nend\n"
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aOther := aPodfile.TargetWithName("Other")
+	if got, want := strings.Join(aOther.LeadingStatements, "|"), "# hook\npost_install do |installer|\nend"; got != want {
+		t.Errorf("LeadingStatements = %q, want %q", got, want)
+	}
+	aOther.AddModule("Baz", "~> 1.0")
+	want := "target 'App' do\n  pod 'Foo'\nend\n\n# hook\npost_install do |installer|\nend\n\ntarget 'Other' do\n  pod 'Bar'\n  pod 'Baz', '~> 1.0'\nend\n"
+	if got := string(aPodfile.Bytes()); got != want {
+		t.Errorf("Bytes() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPodfileBytesAfterRequirementChanges(t *testing.T) {
+	source := `target 'App' do
+  pod 'Foo', '~> 1.0', :subspecs => ['Core', 'Util']
+  pod 'Bar', '>= 1.0', '< 2.0'
+end
+`
+	tests := []struct {
+		name   string
+		change func(aTarget *PodfileTarget)
+		want   string
+	}{
+		{
+			name:   "V replaces the first requirement",
+			change: func(aTarget *PodfileTarget) { aTarget.ModuleWithName("Bar").V = ">= 1.5" },
+			want:   "  pod 'Bar', '>= 1.5', '< 2.0'\n",
+		},
+		{
+			name:   "SetModuleVersion replaces all requirements",
+			change: func(aTarget *PodfileTarget) { aTarget.SetModuleVersion("Bar", "1.8") },
+			want:   "  pod 'Bar', '1.8'\n",
+		},
+		{
+			name:   "subspecs that differ are split",
+			change: func(aTarget *PodfileTarget) { aTarget.SetModuleVersion("Foo/Util", "1.2") },
+			want:   "  pod 'Foo', '~> 1.0', :subspecs => ['Core']\n  pod 'Foo', '1.2', :subspecs => ['Util']\n",
+		},
+		{
+			name:   "removed subspec",
+			change: func(aTarget *PodfileTarget) { aTarget.RemoveModule("Foo/Core") },
+			want:   "  pod 'Foo', '~> 1.0', :subspecs => ['Util']\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aPodfile, err := NewPodfileWithBytes("Podfile", []byte(source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.change(aPodfile.TargetWithName("App"))
+			if got := string(aPodfile.Bytes()); !strings.Contains(got, tt.want) {
+				t.Errorf("Bytes() =\n%s\nwant it to contain\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package pod
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	fdt "github.com/go-hayden-base/foundation"
+)
+
+var regPodspecInterp = regexp.MustCompile(`#\{([^}]*)\}`)
+var regPodspecSpecRef = regexp.MustCompile(`^\s*([a-z_][a-zA-Z0-9_]*)\.(version|name)(\.to_s)?\s*$`)
+
+// Singular forms of the podspec attributes, stored under the plural key as
+// CocoaPods does.
+var podspecAttributeAliases = map[string]string{
+	"author":             "authors",
+	"screenshot":         "screenshots",
+	"framework":          "frameworks",
+	"weak_framework":     "weak_frameworks",
+	"library":            "libraries",
+	"compiler_flag":      "compiler_flags",
+	"resource":           "resources",
+	"resource_bundle":    "resource_bundles",
+	"vendored_framework": "vendored_frameworks",
+	"vendored_library":   "vendored_libraries",
+	"preserve_path":      "preserve_paths",
+	"default_subspec":    "default_subspecs",
+	"swift_version":      "swift_versions",
+	"script_phase":       "script_phases",
+}
+
+// Calls that open a child spec and the key of its list
+var podspecChildCalls = map[string]string{
+	"subspec":   "subspecs",
+	"test_spec": "testspecs",
+	"app_spec":  "appspecs",
+}
+
+// ** Podspec Parser **
+
+// podspecParser evaluates the declarative subset of the podspec DSL with
+// the Podfile parser: `Pod::Spec.new do |s|`, `s.attr = value`, scoped
+// `s.ios.attr = value`, `s.dependency` and subspec blocks.
+type podspecParser struct {
+	*podfileParser
+	root   map[string]interface{}
+	scopes map[string]*podspecScope
+}
+
+type podspecScope struct {
+	name string
+	spec map[string]interface{}
+}
+
+func (s *podspecParser) parse() error {
+	s.skipNewlines()
+	for _, want := range []string{"Pod", "::", "Spec", "."} {
+		t := s.next()
+		if want == "Spec" && t.val == "Specification" {
+			continue
+		}
+		if t.val != want {
+			return s.unsupported(t.line, "缺少 Pod::Spec.new")
+		}
+	}
+	if t := s.peek(); t.kind != tokIdent || t.val != "new" {
+		return s.unsupported(t.line, "缺少 Pod::Spec.new")
+	}
+	call, err := s.parseCall()
+	if err != nil {
+		return err
+	}
+	if !call.block || len(call.args) > 0 || call.opts != nil || len(call.params) != 1 {
+		return s.unsupported(call.line, "Pod::Spec.new 的参数或块错误")
+	}
+	s.root = make(map[string]interface{})
+	s.scopes = map[string]*podspecScope{call.params[0]: {spec: s.root}}
+	if err := s.parseSpecBody(call.params[0]); err != nil {
+		return err
+	}
+	s.skipNewlines()
+	if t := s.peek(); t.kind != tokEOF {
+		return s.unsupported(t.line, "Pod::Spec.new 之后有多余的语句")
+	}
+	return nil
+}
+
+func (s *podspecParser) parseSpecBody(name string) error {
+	scope := s.scopes[name]
+	for {
+		s.skipNewlines()
+		t := s.next()
+		if t.kind == tokEOF {
+			return s.unsupported(t.line, "缺少end")
+		}
+		if t.kind == tokIdent && t.val == "end" {
+			return nil
+		}
+		if _, ok := s.scopes[t.val]; t.kind != tokIdent || !ok || !s.isDot(s.next()) {
+			return s.unsupported(t.line, "无法识别的语句")
+		}
+		if t.val != name {
+			return s.unsupported(t.line, "不支持在子spec中修改 "+t.val)
+		}
+		m := s.next()
+		if m.kind != tokIdent {
+			return s.unsupported(m.line, "无法识别的属性")
+		}
+		platform := SpecPlatformAny
+		if p := normalizeSpecPlatform(m.val); fdt.SliceContainsStr(p, SpecPlatforms) && s.isDot(s.peek()) {
+			s.next()
+			platform = p
+			if m = s.next(); m.kind != tokIdent {
+				return s.unsupported(m.line, "无法识别的属性")
+			}
+		}
+		if p := s.peek(); p.kind == tokPunct && p.val == "=" {
+			s.next()
+			values, err := s.parseValues()
+			if err != nil {
+				return err
+			}
+			if err := s.assign(scope.spec, platform, m, values); err != nil {
+				return err
+			}
+			continue
+		}
+		s.pos--
+		call, err := s.parseCall()
+		if err != nil {
+			return err
+		}
+		if err := s.evalSpecCall(scope, platform, call); err != nil {
+			return err
+		}
+	}
+}
+
+// parseValues parses the right side of an assignment, several values
+// separated by commas make a list.
+func (s *podspecParser) parseValues() ([]interface{}, error) {
+	values := make([]interface{}, 0, 1)
+	for {
+		v, err := s.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		p := s.peek()
+		if p.kind == tokPunct && p.val == "," {
+			s.next()
+			s.skipNewlines()
+			continue
+		}
+		if p.kind != tokNewline && p.kind != tokEOF {
+			return nil, s.unsupported(p.line, "无法求值的表达式 "+p.val)
+		}
+		return values, nil
+	}
+}
+
+func (s *podspecParser) assign(spec map[string]interface{}, platform string, attr *rbToken, values []interface{}) error {
+	key := attr.val
+	if alias, ok := podspecAttributeAliases[key]; ok {
+		key = alias
+	}
+	var value interface{}
+	if len(values) == 1 {
+		value = podspecJSONValue(values[0])
+	} else {
+		value = podspecJSONValue(values)
+	}
+	switch {
+	case key == "platform" && platform == SpecPlatformAny:
+		name, ok := values[0].(string)
+		if !ok || len(values) > 2 {
+			return s.unsupported(attr.line, "platform 参数错误")
+		}
+		var version interface{}
+		if len(values) == 2 {
+			version = podspecJSONValue(values[1])
+		}
+		podspecHash(spec, "platforms")[normalizeSpecPlatform(name)] = version
+	case key == "deployment_target" && platform != SpecPlatformAny:
+		podspecHash(spec, "platforms")[platform] = value
+	case platform != SpecPlatformAny:
+		podspecHash(spec, platform)[key] = value
+	default:
+		spec[key] = value
+	}
+	return nil
+}
+
+func (s *podspecParser) evalSpecCall(scope *podspecScope, platform string, call *rbCall) error {
+	if listKey, ok := podspecChildCalls[call.name]; ok && platform == SpecPlatformAny {
+		name, ok := stringArg(call, 0)
+		if !ok || !call.block || len(call.params) != 1 || call.opts != nil {
+			return s.unsupported(call.line, call.name+" 参数错误")
+		}
+		if _, ok := s.scopes[call.params[0]]; ok {
+			return s.unsupported(call.line, "块参数 "+call.params[0]+" 重名")
+		}
+		child := map[string]interface{}{"name": name}
+		s.scopes[call.params[0]] = &podspecScope{name: scope.name + "/" + name, spec: child}
+		err := s.parseSpecBody(call.params[0])
+		delete(s.scopes, call.params[0])
+		if err != nil {
+			return err
+		}
+		if _, ok := child["test_type"]; !ok && call.name == "test_spec" {
+			child["test_type"] = "unit"
+		}
+		list, _ := scope.spec[listKey].([]interface{})
+		scope.spec[listKey] = append(list, child)
+		return nil
+	}
+	if call.block {
+		return s.unsupported(call.line, call.name+" 不支持块")
+	}
+	if call.name != "dependency" {
+		return s.unsupported(call.line, "不支持的方法 "+call.name)
+	}
+	name, ok := stringArg(call, 0)
+	if !ok || call.opts != nil {
+		return s.unsupported(call.line, "dependency 参数错误")
+	}
+	requirements := make([]interface{}, 0, len(call.args)-1)
+	for i := 1; i < len(call.args); i++ {
+		r, ok := stringArg(call, i)
+		if !ok {
+			return s.unsupported(call.line, "dependency 版本参数错误")
+		}
+		requirements = append(requirements, r)
+	}
+	target := scope.spec
+	if platform != SpecPlatformAny {
+		target = podspecHash(scope.spec, platform)
+	}
+	podspecHash(target, "dependencies")[name] = requirements
+	return nil
+}
+
+// evalValue evaluates what the Podfile parser does not: numbers, symbols as
+// plain strings, references to the name or version of a spec and the
+// interpolation of them.
+func (s *podspecParser) evalValue(t *rbToken) (interface{}, bool, error) {
+	switch t.kind {
+	case tokSymbol:
+		return t.val, true, nil
+	case tokNumber:
+		return json.Number(strings.Replace(t.val, "_", "", -1)), true, nil
+	case tokString:
+		if !t.interp {
+			return nil, false, nil
+		}
+		var err error
+		res := regPodspecInterp.ReplaceAllStringFunc(t.val, func(m string) string {
+			v, e := s.specRef(t.line, regPodspecInterp.FindStringSubmatch(m)[1])
+			if e != nil && err == nil {
+				err = e
+			}
+			return v
+		})
+		return res, true, err
+	case tokIdent:
+		if _, ok := s.scopes[t.val]; !ok {
+			return nil, false, nil
+		}
+		expr := t.val
+		for s.isDot(s.peek()) && s.tokens[s.pos+1].kind == tokIdent {
+			expr += "." + s.tokens[s.pos+1].val
+			s.pos += 2
+		}
+		v, err := s.specRef(t.line, expr)
+		return v, true, err
+	}
+	return nil, false, nil
+}
+
+// specRef evaluates "s.version" or "s.name", with an optional ".to_s".
+func (s *podspecParser) specRef(line int, expr string) (string, error) {
+	m := regPodspecSpecRef.FindStringSubmatch(expr)
+	if m == nil {
+		return "", s.unsupported(line, "无法求值的表达式 "+strings.TrimSpace(expr))
+	}
+	scope, ok := s.scopes[m[1]]
+	if !ok {
+		return "", s.unsupported(line, "未定义的变量 "+m[1])
+	}
+	key := m[2]
+	v, ok := s.root[key].(string)
+	if !ok {
+		return "", s.unsupported(line, m[1]+"."+key+" 尚未定义")
+	}
+	if key == "name" {
+		v += scope.name
+	}
+	return v, nil
+}
+
+func (s *podspecParser) isDot(t *rbToken) bool {
+	return t.kind == tokOther && t.val == "."
+}
+
+// ** PodspecUnsupportedError Impl **
+func (s *PodspecUnsupportedError) Error() string {
+	return "无法解析Podspec: " + s.FilePath + ":" + strconv.Itoa(s.Line) + " " + s.Reason
+}
+
+// ** Func Public **
+
+// ParsePodspec evaluates a Ruby .podspec without CocoaPods. It returns a
+// *PodspecUnsupportedError when the file needs a Ruby interpreter, callers
+// may then fall back to `pod ipc spec`.
+func ParsePodspec(filePath string) (*Spec, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	aSpec, err := NewSpecWithPodspecBytes(filePath, b)
+	if err != nil {
+		return nil, err
+	}
+	aSpec.FilePath = filePath
+	return aSpec, nil
+}
+
+func NewSpecWithPodspecBytes(filePath string, b []byte) (*Spec, error) {
+	b, err := PodspecJSON(filePath, b)
+	if err != nil {
+		return nil, err
+	}
+	return NewSpecWithJSONBytes(b)
+}
+
+// PodspecJSON converts the source of a .podspec to the JSON `pod ipc spec`
+// prints for it.
+func PodspecJSON(filePath string, b []byte) ([]byte, error) {
+	parser := &podspecParser{podfileParser: &podfileParser{filePath: filePath, src: b, tokens: rbTokenize(b)}}
+	parser.valueHook = parser.evalValue
+	if err := parser.parse(); err != nil {
+		if e, ok := err.(*PodfileUnsupportedError); ok {
+			return nil, &PodspecUnsupportedError{FilePath: e.FilePath, Line: e.Line, Reason: e.Reason}
+		}
+		return nil, err
+	}
+	return json.Marshal(parser.root)
+}
+
+// ** Func Private **
+
+// podspecJSONValue turns a parsed Ruby value into its JSON form, symbol keys
+// of a hash lose their colon.
+func podspecJSONValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		res := make([]interface{}, 0, len(t))
+		for _, item := range t {
+			res = append(res, podspecJSONValue(item))
+		}
+		return res
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(t))
+		for key, val := range t {
+			if ks, ok := key.(string); ok {
+				res[strings.TrimPrefix(ks, ":")] = podspecJSONValue(val)
+			}
+		}
+		return res
+	}
+	return v
+}
+
+func podspecHash(spec map[string]interface{}, key string) map[string]interface{} {
+	m, ok := spec[key].(map[string]interface{})
+	if !ok {
+		m = make(map[string]interface{})
+		spec[key] = m
+	}
+	return m
+}
@@ -0,0 +1,137 @@
+package pod
+
+import (
+	"testing"
+)
+
+const testPodspec = `Pod::Spec.new do |s|
+  s.name         = 'Foo'
+  s.version      = '1.2.0'
+  s.summary      = 'A short summary.'
+  s.description  = <<-DESC
+    A longer description.
+  DESC
+  s.homepage     = 'https://example.com/Foo'
+  s.license      = { :type => 'MIT', :file => 'LICENSE' }
+  s.authors      = { 'Jane' => 'jane@example.com' }
+  s.source       = { :git => 'https://example.com/Foo.git', :tag => "v#{s.version}" }
+  s.ios.deployment_target = '10.0'
+  s.osx.deployment_target = '10.12'
+  s.requires_arc = true
+  s.default_subspecs = 'Core'
+
+  s.subspec 'Core' do |ss|
+    ss.source_files = 'Foo/Core/**/*.{h,m}'
+    ss.dependency 'Bar', '~> 2.0'
+  end
+
+  s.subspec 'UI' do |ss|
+    ss.source_files = 'Foo/UI/**/*.{h,m}'
+    ss.ios.frameworks = 'UIKit'
+    ss.dependency 'Foo/Core'
+  end
+
+  s.test_spec 'Tests' do |ts|
+    ts.source_files = 'Tests/**/*.m'
+  end
+end
+`
+
+func TestPodspecJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			name:   "full podspec",
+			source: testPodspec,
+			want: `{"name": "Foo", "version": "1.2.0", "summary": "A short summary.",
+				"description": "    A longer description.\n", "homepage": "https://example.com/Foo",
+				"license": {"type": "MIT", "file": "LICENSE"}, "authors": {"Jane": "jane@example.com"},
+				"source": {"git": "https://example.com/Foo.git", "tag": "v1.2.0"},
+				"platforms": {"ios": "10.0", "osx": "10.12"}, "requires_arc": true, "default_subspecs": "Core",
+				"subspecs": [
+					{"name": "Core", "source_files": "Foo/Core/**/*.{h,m}", "dependencies": {"Bar": ["~> 2.0"]}},
+					{"name": "UI", "source_files": "Foo/UI/**/*.{h,m}", "ios": {"frameworks": "UIKit"}, "dependencies": {"Foo/Core": []}}
+				],
+				"testspecs": [{"name": "Tests", "test_type": "unit", "source_files": "Tests/**/*.m"}]}`,
+		},
+		{
+			name: "attributes",
+			source: `Pod::Spec.new do |s|
+  s.name = 'Foo'
+  s.version = '1.0'
+  s.source = { :git => 'https://example.com/Foo.git', :tag => s.version.to_s }
+  s.platform = :ios, '9.0'
+  s.source_files = 'Foo/*.{h,m}'
+end
+`,
+			want: `{"name": "Foo", "version": "1.0",
+				"source": {"git": "https://example.com/Foo.git", "tag": "1.0"},
+				"platforms": {"ios": "9.0"}, "source_files": "Foo/*.{h,m}"}`,
+		},
+		{
+			name: "dependencies and subspecs",
+			source: `Pod::Spec.new do |s|
+  s.name = 'Foo'
+  s.version = '1.0'
+  s.dependency 'Bar'
+  s.subspec 'Core' do |ss|
+    ss.dependency 'Baz', '>= 1.0', '< 2.0'
+  end
+end
+`,
+			want: `{"name": "Foo", "version": "1.0", "dependencies": {"Bar": []},
+				"subspecs": [{"name": "Core", "dependencies": {"Baz": [">= 1.0", "< 2.0"]}}]}`,
+		},
+		{
+			name: "heredoc",
+			source: `Pod::Spec.new do |s|
+  s.name = 'Foo'
+  s.version = '1.0'
+  s.description = <<-DESC
+    Line one.
+  DESC
+end
+`,
+			want: `{"name": "Foo", "version": "1.0", "description": "    Line one.\n"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := PodspecJSON("Foo.podspec", []byte(tt.source))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := normalizedJSON(t, b), normalizedJSON(t, []byte(tt.want)); got != want {
+				t.Errorf("PodspecJSON() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestPodspecJSONUnsupported(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		line   int
+	}{
+		{name: "no Pod::Spec.new", source: "puts 'Foo'\n", line: 1},
+		{name: "unknown method", source: "Pod::Spec.new do |s|\n  s.name = 'Foo'\n  s.prepare\nend\n", line: 3},
+		{name: "reading a file", source: "Pod::Spec.new do |s|\n  s.name = 'Foo'\n  s.version = File.read('VERSION')\nend\n", line: 3},
+		{name: "statement after the spec", source: "Pod::Spec.new do |s|\n  s.name = 'Foo'\nend\nputs 'done'\n", line: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PodspecJSON("Foo.podspec", []byte(tt.source))
+			uErr, ok := err.(*PodspecUnsupportedError)
+			if !ok {
+				t.Fatalf("error = %v, want a *PodspecUnsupportedError", err)
+			}
+			if uErr.Line != tt.line {
+				t.Errorf("error line = %d (%s), want %d", uErr.Line, uErr.Reason, tt.line)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package pod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+const podIndexCacheVersion = 2
+
+// ** Pod Cache Impl **
+
+// IndexWithCache indexes the repos like PodIndex and parses their specs, using
+// one cache file per repo in cacheDir. Modules whose directory and spec files
+// have the same mtime and size as in the cache are loaded from it, only the
+// others are read and parsed again. The cache lists every module and version,
+// filterFunc is applied after loading, and specs that failed to parse are not
+// cached. The changes are returned per repo name.
+func (s *Pod) IndexWithCache(root string, repos []string, cacheDir string, filterFunc func(p string, level PodLevel) bool) (map[string]*PodIndexChanges, error) {
+	if len(repos) == 0 {
+		return nil, errors.New("没有索引的仓库！")
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, err
+	}
+	podrepos := make([]*PodRepo, 0, len(repos))
+	changes := make(map[string]*PodIndexChanges)
+	for _, rn := range repos {
+		repo, err := NewPodRepo(rn, path.Join(root, rn))
+		if err != nil {
+			return nil, err
+		}
+		cacheFile := path.Join(cacheDir, rn+".index.json")
+		c, cache, err := repo.indexWithCache(loadIndexCache(cacheFile, repo.Root), s.Runner, filterFunc)
+		if err != nil {
+			return nil, err
+		}
+		if err := cache.save(cacheFile); err != nil {
+			return nil, err
+		}
+		changes[rn] = c
+		podrepos = append(podrepos, repo)
+	}
+	s.PodRepos = podrepos
+	s.resetModuleMap()
+	return changes, nil
+}
+
+// indexWithCache indexes the repo and returns the cache to save, which keeps
+// the modules and versions filterFunc leaves out.
+func (s *PodRepo) indexWithCache(cache *p_index_cache, runner CommandRunner, filterFunc func(p string, level PodLevel) bool) (*PodIndexChanges, *p_index_cache, error) {
+	changes := new(PodIndexChanges)
+	next := &p_index_cache{Version: podIndexCacheVersion, Root: s.Root, Modules: make(map[string]*p_cache_module)}
+	modules := make([]*PodModule, 0, len(cache.Modules))
+	toParse := make([]*PodModuleVersion, 0, 10)
+	parsed := make(map[*PodModuleVersion]*p_cache_version)
+	seen := make(map[string]bool)
+	err := s.enumerateModuleDirs(s.Root, 0, func(name, mp string) {
+		seen[name] = true
+		cm := cache.Modules[name]
+		if filterFunc != nil && filterFunc(mp, ENUM_POD_LEVEL_MODULE) {
+			// Keep the entry so that the module is neither removed nor
+			// parsed again once the filter lets it through
+			if cm != nil {
+				next.Modules[name] = cm
+			}
+			return
+		}
+		if cm == nil || !cm.isUpToDate(mp) {
+			if cm == nil {
+				changes.Added = append(changes.Added, name)
+			} else {
+				changes.Updated = append(changes.Updated, name)
+			}
+			if cm = newCacheModule(name, mp, cm); cm == nil {
+				return
+			}
+		}
+		next.Modules[name] = cm
+		module := cm.podModule(name, mp, filterFunc, func(version *PodModuleVersion, cv *p_cache_version) {
+			toParse = append(toParse, version)
+			parsed[version] = cv
+		})
+		if module != nil {
+			modules = append(modules, module)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for name := range cache.Modules {
+		if !seen[name] {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+	parseModuleVersions(toParse, runner)
+	for version, cv := range parsed {
+		if version.Err != nil || version.Podspec == nil {
+			continue
+		}
+		if b, err := version.Podspec.JSON(); err == nil {
+			cv.Spec = b
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Updated)
+	sort.Strings(changes.Removed)
+	s.Modules = modules
+	return changes, next, nil
+}
+
+// ** p_index_cache Impl **
+func (s *p_index_cache) save(cacheFile string) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := cacheFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cacheFile)
+}
+
+// ** p_cache_module Impl **
+func (s *p_cache_module) isUpToDate(mp string) bool {
+	fi, err := os.Stat(mp)
+	if err != nil || fi.ModTime().UnixNano() != s.ModTime {
+		return false
+	}
+	for _, cv := range s.Versions {
+		if !cv.matchFile(path.Join(mp, cv.Name, cv.FileName)) {
+			return false
+		}
+	}
+	return true
+}
+
+// podModule restores the versions filterFunc keeps, parse is called for the
+// ones without a cached spec.
+func (s *p_cache_module) podModule(name, mp string, filterFunc func(p string, level PodLevel) bool, parse func(version *PodModuleVersion, cv *p_cache_version)) *PodModule {
+	module := new(PodModule)
+	module.Name = name
+	module.Root = mp
+	for _, cv := range s.Versions {
+		pwd := path.Join(mp, cv.Name)
+		if filterFunc != nil && filterFunc(pwd, ENUM_POD_LEVEL_VERSION) {
+			continue
+		}
+		version := new(PodModuleVersion)
+		version.Name = cv.Name
+		version.Root = pwd
+		version.FileName = cv.FileName
+		if !cv.restore(version) {
+			parse(version, cv)
+		}
+		module.Versions = append(module.Versions, version)
+	}
+	if len(module.Versions) == 0 {
+		return nil
+	}
+	return module
+}
+
+// specOf returns the cached spec of version when its file did not change.
+func (s *p_cache_module) specOf(version *PodModuleVersion) json.RawMessage {
+	for _, cv := range s.Versions {
+		if cv.Name == version.Name && cv.FileName == version.FileName && cv.matchFile(path.Join(version.Root, version.FileName)) {
+			return cv.Spec
+		}
+	}
+	return nil
+}
+
+// ** p_cache_version Impl **
+func (s *p_cache_version) matchFile(p string) bool {
+	fi, err := os.Stat(p)
+	return err == nil && fi.Size() == s.Size && fi.ModTime().UnixNano() == s.ModTime
+}
+
+func (s *p_cache_version) restore(version *PodModuleVersion) bool {
+	if len(s.Spec) == 0 {
+		return false
+	}
+	aSpec, err := NewSpecWithJSONBytes(s.Spec)
+	if err != nil {
+		return false
+	}
+	aSpec.FilePath = path.Join(version.Root, version.FileName)
+	version.Podspec = aSpec
+	return true
+}
+
+// ** Func Public **
+func PodIndexWithCache(podRoot string, repos []string, cacheDir string, filterFunc func(p string, level PodLevel) bool) (*Pod, map[string]*PodIndexChanges, error) {
+	aPod := new(Pod)
+	changes, err := aPod.IndexWithCache(podRoot, repos, cacheDir, filterFunc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aPod, changes, nil
+}
+
+// ** Func Private **
+
+// newCacheModule lists every version of the module at mp, keeping the specs
+// of old whose files did not change. It returns nil when the module has no
+// version.
+func newCacheModule(name, mp string, old *p_cache_module) *p_cache_module {
+	fi, err := os.Stat(mp)
+	if err != nil {
+		return nil
+	}
+	module := new(PodModule)
+	module.Name = name
+	module.Root = mp
+	if module.index(nil) != nil {
+		return nil
+	}
+	cm := &p_cache_module{ModTime: fi.ModTime().UnixNano()}
+	for _, version := range module.Versions {
+		fi, err := os.Stat(path.Join(version.Root, version.FileName))
+		if err != nil {
+			continue
+		}
+		cv := &p_cache_version{Name: version.Name, FileName: version.FileName, Size: fi.Size(), ModTime: fi.ModTime().UnixNano()}
+		if old != nil {
+			cv.Spec = old.specOf(version)
+		}
+		cm.Versions = append(cm.Versions, cv)
+	}
+	return cm
+}
+
+func loadIndexCache(cacheFile, root string) *p_index_cache {
+	empty := &p_index_cache{Version: podIndexCacheVersion, Root: root, Modules: make(map[string]*p_cache_module)}
+	b, err := ioutil.ReadFile(cacheFile)
+	if err != nil {
+		return empty
+	}
+	var cache *p_index_cache
+	if json.Unmarshal(b, &cache) != nil || cache == nil || cache.Version != podIndexCacheVersion || cache.Root != root || cache.Modules == nil {
+		return empty
+	}
+	return cache
+}
+
+func parseModuleVersions(versions []*PodModuleVersion, runner CommandRunner) {
+	threadNum := runtime.NumCPU()
+	c := make(chan bool, threadNum)
+	var wg sync.WaitGroup
+	for _, version := range versions {
+		wg.Add(1)
+		c <- true
+		go func(v *PodModuleVersion) {
+			defer func() {
+				<-c
+				wg.Done()
+			}()
+			v.Podspec, v.Err = ReadSpecWithRunner(context.Background(), runner, path.Join(v.Root, v.FileName))
+		}(version)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,223 @@
+package pod
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testChangesString(c *PodIndexChanges) string {
+	return "added:" + strings.Join(c.Added, ",") + " updated:" + strings.Join(c.Updated, ",") + " removed:" + strings.Join(c.Removed, ",")
+}
+
+func TestPodIndexWithCacheChanges(t *testing.T) {
+	root := writeTestRepo(t, map[string]string{
+		"Foo/1.0/Foo.podspec.json": `{"name": "Foo", "version": "1.0"}`,
+		"Bar/1.0/Bar.podspec.json": `{"name": "Bar", "version": "1.0"}`,
+	})
+	repoDir := path.Join(root, "test")
+	cacheDir := t.TempDir()
+	touch := func(p string) {
+		later := time.Now().Add(time.Hour)
+		if err := os.Chtimes(p, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name   string
+		change func()
+		want   string
+	}{
+		{name: "first run", want: "added:Bar,Foo updated: removed:"},
+		{name: "unchanged", want: "added: updated: removed:"},
+		{
+			name: "new version",
+			change: func() {
+				p := path.Join(repoDir, "Foo", "1.1", "Foo.podspec.json")
+				if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(p, []byte(`{"name": "Foo", "version": "1.1"}`), 0644); err != nil {
+					t.Fatal(err)
+				}
+				touch(path.Join(repoDir, "Foo"))
+			},
+			want: "added: updated:Foo removed:",
+		},
+		{
+			name: "edited spec",
+			change: func() {
+				p := path.Join(repoDir, "Bar", "1.0", "Bar.podspec.json")
+				if err := ioutil.WriteFile(p, []byte(`{"name": "Bar", "version": "1.0", "dependencies": {"Foo": []}}`), 0644); err != nil {
+					t.Fatal(err)
+				}
+				touch(p)
+			},
+			want: "added: updated:Bar removed:",
+		},
+		{
+			name: "removed module",
+			change: func() {
+				if err := os.RemoveAll(path.Join(repoDir, "Foo")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: "added: updated: removed:Foo",
+		},
+	}
+	for _, tt := range tests {
+		if tt.change != nil {
+			tt.change()
+		}
+		aPod, changes, err := PodIndexWithCache(root, []string{"test"}, cacheDir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := testChangesString(changes["test"]); got != tt.want {
+			t.Errorf("%s: changes = %s, want %s", tt.name, got, tt.want)
+		}
+		for _, aModule := range aPod.PodRepos[0].Modules {
+			for _, version := range aModule.Versions {
+				if version.Podspec == nil || version.Podspec.Version != version.Name {
+					t.Errorf("%s: %s %s has spec %v", tt.name, aModule.Name, version.Name, version.Podspec)
+				}
+			}
+		}
+	}
+	if aPod, _, _ := PodIndexWithCache(root, []string{"test"}, cacheDir, nil); aPod.PodRepos[0].Modules[0].Versions[0].Podspec.Dependences == nil {
+		t.Error("the edited spec is not cached")
+	}
+}
+
+func cachedVersionNames(aPod *Pod, module string) []string {
+	var res []string
+	for _, repo := range aPod.PodRepos {
+		for _, aModule := range repo.Modules {
+			if aModule.Name != module {
+				continue
+			}
+			for _, version := range aModule.Versions {
+				res = append(res, version.Name)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestIndexWithCacheFilter(t *testing.T) {
+	root := writeTestRepo(t, map[string]string{
+		"Foo/1.0/Foo.podspec.json": `{"name": "Foo", "version": "1.0"}`,
+		"Foo/1.2/Foo.podspec.json": `{"name": "Foo", "version": "1.2"}`,
+	})
+	cacheDir := t.TempDir()
+	skip12 := func(p string, level PodLevel) bool {
+		return level == ENUM_POD_LEVEL_VERSION && path.Base(p) == "1.2"
+	}
+	tests := []struct {
+		name   string
+		filter func(p string, level PodLevel) bool
+		want   string
+	}{
+		{name: "filtered", filter: skip12, want: "1.0"},
+		{name: "unfiltered from the cache", filter: nil, want: "1.0,1.2"},
+		{name: "filtered again", filter: skip12, want: "1.0"},
+	}
+	for _, tt := range tests {
+		aPod, _, err := PodIndexWithCache(root, []string{"test"}, cacheDir, tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Join(cachedVersionNames(aPod, "Foo"), ","); got != tt.want {
+			t.Errorf("%s: versions = %s, want %s", tt.name, got, tt.want)
+		}
+		for _, repo := range aPod.PodRepos {
+			for _, aModule := range repo.Modules {
+				for _, version := range aModule.Versions {
+					if version.Podspec == nil {
+						t.Errorf("%s: %s %s has no spec", tt.name, aModule.Name, version.Name)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestIndexWithCacheModuleFilter(t *testing.T) {
+	root := writeTestRepo(t, map[string]string{
+		"Foo/1.0/Foo.podspec.json": `{"name": "Foo", "version": "1.0"}`,
+		"Bar/1.0/Bar.podspec.json": `{"name": "Bar", "version": "1.0"}`,
+	})
+	cacheDir := t.TempDir()
+	skipBar := func(p string, level PodLevel) bool {
+		return level == ENUM_POD_LEVEL_MODULE && path.Base(p) == "Bar"
+	}
+	tests := []struct {
+		name    string
+		filter  func(p string, level PodLevel) bool
+		want    string
+		changes string
+	}{
+		{name: "unfiltered", filter: nil, want: "Bar:1.0 Foo:1.0", changes: "added:Bar,Foo updated: removed:"},
+		{name: "filtered", filter: skipBar, want: "Bar: Foo:1.0", changes: "added: updated: removed:"},
+		{name: "unfiltered from the cache", filter: nil, want: "Bar:1.0 Foo:1.0", changes: "added: updated: removed:"},
+	}
+	for _, tt := range tests {
+		aPod, changes, err := PodIndexWithCache(root, []string{"test"}, cacheDir, tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := "Bar:" + strings.Join(cachedVersionNames(aPod, "Bar"), ",") + " Foo:" + strings.Join(cachedVersionNames(aPod, "Foo"), ",")
+		if got != tt.want {
+			t.Errorf("%s: versions = %s, want %s", tt.name, got, tt.want)
+		}
+		if got := testChangesString(changes["test"]); got != tt.changes {
+			t.Errorf("%s: changes = %s, want %s", tt.name, got, tt.changes)
+		}
+	}
+}
+
+func TestIndexWithCacheDoesNotKeepErrors(t *testing.T) {
+	broken := `{"name": "Foo", "version": "1.0",}`
+	fixed := `{"name": "Foo", "version": "1.0"}`
+	fixed += strings.Repeat(" ", len(broken)-len(fixed))
+	root := writeTestRepo(t, map[string]string{"Foo/1.0/Foo.podspec.json": broken})
+	cacheDir := t.TempDir()
+	specFile := path.Join(root, "test", "Foo", "1.0", "Foo.podspec.json")
+
+	aPod, _, err := PodIndexWithCache(root, []string{"test"}, cacheDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version := aPod.PodRepos[0].Modules[0].Versions[0]; version.Err == nil {
+		t.Fatal("want an error for the broken spec")
+	}
+
+	// Fix the spec without changing its size or mtime
+	fi, err := os.Stat(specFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(specFile, []byte(fixed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(specFile, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+
+	aPod, changes, err := PodIndexWithCache(root, []string{"test"}, cacheDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := changes["test"]; len(c.Added)+len(c.Updated)+len(c.Removed) > 0 {
+		t.Errorf("changes = %+v, want none", c)
+	}
+	version := aPod.PodRepos[0].Modules[0].Versions[0]
+	if version.Err != nil || version.Podspec == nil || version.Podspec.Version != "1.0" {
+		t.Errorf("spec = %v, %v, want it read again", version.Podspec, version.Err)
+	}
+}
@@ -1,13 +1,22 @@
 package pod
 
 import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"errors"
 	"io/ioutil"
 	"path"
+	"regexp"
+	"strings"
 
+	fdt "github.com/go-hayden-base/foundation"
 	"github.com/go-hayden-base/fs"
+	yaml "gopkg.in/yaml.v2"
 )
 
+var regHexShard = regexp.MustCompile(`^[0-9a-f]{1,3}$`)
+
 const (
 	ENUM_POD_LEVEL_REPO = iota
 	ENUM_POD_LEVEL_MODULE
@@ -38,54 +47,75 @@ func (s *Pod) index(root string, repos []string, filterFunc func(p string, level
 	}
 	podrepos := make([]*PodRepo, 0, len(repos))
 	for _, rn := range repos {
-		var reporoot string
-		if rn == "master" {
-			reporoot = path.Join(root, rn, "Specs")
-		} else {
-			reporoot = path.Join(root, rn)
-		}
-		if !fs.DirectoryExists(reporoot) {
-			return errors.New("仓库不存在[" + reporoot + "]")
+		repo, err := NewPodRepo(rn, path.Join(root, rn))
+		if err != nil {
+			return err
 		}
-		repo := new(PodRepo)
-		repo.Name = rn
-		repo.Root = reporoot
 		if err := repo.index(filterFunc); err == nil {
 			podrepos = append(podrepos, repo)
 		}
 	}
 
 	s.PodRepos = podrepos
+	s.resetModuleMap()
 	return nil
 }
 
 // ** PodRepo Impl **
-func (s *PodRepo) index(filterFunc func(p string, level PodLevel) bool) error {
-	dirs, err := ioutil.ReadDir(s.Root)
-	if err != nil {
-		return err
+
+// ModuleDir returns the directory of the module, computed from its name for
+// a sharded repo.
+func (s *PodRepo) ModuleDir(name string) string {
+	name = fdt.StrSplitFirst(name, "/")
+	if len(s.PrefixLengths) == 0 {
+		return path.Join(s.Root, name)
 	}
-	l := len(dirs)
-	modules := make([]*PodModule, 0, l)
-	for _, f := range dirs {
-		if !f.IsDir() {
-			continue
-		}
-		if f.Name() == ".git" {
-			continue
+	sum := md5.Sum([]byte(name))
+	hash := hex.EncodeToString(sum[:])
+	elems := make([]string, 0, len(s.PrefixLengths)+2)
+	elems = append(elems, s.Root)
+	for _, l := range s.PrefixLengths {
+		if l > len(hash) {
+			break
 		}
+		elems = append(elems, hash[:l])
+		hash = hash[l:]
+	}
+	elems = append(elems, name)
+	return path.Join(elems...)
+}
+
+// IndexModule indexes a single module without walking the repo.
+func (s *PodRepo) IndexModule(name string, filterFunc func(p string, level PodLevel) bool) (*PodModule, error) {
+	mp := s.ModuleDir(name)
+	if !fs.DirectoryExists(mp) {
+		return nil, errors.New("模块不存在[" + mp + "]")
+	}
+	module := new(PodModule)
+	module.Name = fdt.StrSplitFirst(name, "/")
+	module.Root = mp
+	if err := module.index(filterFunc); err != nil {
+		return nil, err
+	}
+	return module, nil
+}
 
-		mp := path.Join(s.Root, f.Name())
+func (s *PodRepo) index(filterFunc func(p string, level PodLevel) bool) error {
+	modules := make([]*PodModule, 0, 100)
+	err := s.enumerateModuleDirs(s.Root, 0, func(name, mp string) {
 		if filterFunc != nil && filterFunc(mp, ENUM_POD_LEVEL_MODULE) {
-			continue
+			return
 		}
 		module := new(PodModule)
-		module.Name = f.Name()
+		module.Name = name
 		module.Root = mp
 		e := module.index(filterFunc)
 		if e == nil {
 			modules = append(modules, module)
 		}
+	})
+	if err != nil {
+		return err
 	}
 	if len(modules) == 0 {
 		return errors.New("Warn: No module in repo " + s.Name + " [" + s.Root + "]")
@@ -94,6 +124,30 @@ func (s *PodRepo) index(filterFunc func(p string, level PodLevel) bool) error {
 	return nil
 }
 
+func (s *PodRepo) enumerateModuleDirs(dir string, level int, f func(name, mp string)) error {
+	dirs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, fi := range dirs {
+		if !fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		p := path.Join(dir, fi.Name())
+		if level < len(s.PrefixLengths) {
+			if len(fi.Name()) != s.PrefixLengths[level] {
+				continue
+			}
+			if err := s.enumerateModuleDirs(p, level+1, f); err != nil {
+				return err
+			}
+			continue
+		}
+		f(fi.Name(), p)
+	}
+	return nil
+}
+
 // ** PodModule Impl **
 func (s *PodModule) index(filterFunc func(p string, level PodLevel) bool) error {
 	dirs, err := ioutil.ReadDir(s.Root)
@@ -150,6 +204,25 @@ func (s *PodModuleVersion) index() error {
 }
 
 // ** Func Public **
+
+// NewPodRepo creates a repo rooted at repoDir without indexing it. Modules
+// are looked up in the Specs directory when it exists, like CocoaPods does,
+// and the sharding is read from CocoaPods-version.yml or detected from the
+// directory names.
+func NewPodRepo(name, repoDir string) (*PodRepo, error) {
+	if !fs.DirectoryExists(repoDir) {
+		return nil, errors.New("仓库不存在[" + repoDir + "]")
+	}
+	repo := new(PodRepo)
+	repo.Name = name
+	repo.Root = repoDir
+	if specsDir := path.Join(repoDir, "Specs"); fs.DirectoryExists(specsDir) {
+		repo.Root = specsDir
+	}
+	repo.PrefixLengths = detectPrefixLengths(repoDir, repo.Root)
+	return repo, nil
+}
+
 func PodIndex(podRoot string, repos []string, filterFunc func(p string, level PodLevel) bool) (*Pod, error) {
 	aPod := new(Pod)
 	if err := aPod.index(podRoot, repos, filterFunc); err != nil {
@@ -173,7 +246,10 @@ func ResolvePodSpecs(aPod *Pod, threadNum int, callbackFunc func(aSpec []*Spec),
 
 	asyncReadFunc := func(v *PodModuleVersion, c chan *Spec) {
 		specPath := path.Join(v.Root, v.FileName)
-		aSpec, err := ReadSpec(specPath)
+		aSpec, err := v.Podspec, v.Err
+		if aSpec == nil && err == nil {
+			aSpec, err = ReadSpecWithRunner(context.Background(), aPod.Runner, specPath)
+		}
 		if err == nil {
 			if logFunc != nil {
 				logFunc(true, "解析Spec成功: "+specPath)
@@ -223,3 +299,97 @@ func ResolvePodSpecs(aPod *Pod, threadNum int, callbackFunc func(aSpec []*Spec),
 
 	dequeueFunc(idx, cSpecs)
 }
+
+// ** Func Private **
+func detectPrefixLengths(repoDir, specsDir string) []int {
+	if b, err := ioutil.ReadFile(path.Join(repoDir, "CocoaPods-version.yml")); err == nil {
+		var v *p_repo_version
+		if yaml.Unmarshal(b, &v) == nil && v != nil {
+			return v.Prefix_lengths
+		}
+	}
+	// Without the version file, the levels of hex names of the same length
+	// under Specs are shard levels, as long as the directory they lead to
+	// holds modules. A repo without Specs is flat.
+	if specsDir == repoDir {
+		return nil
+	}
+	var res []int
+	levelDirs := []string{specsDir}
+	for len(res) < 4 {
+		l, next := hexShardLevel(levelDirs[len(levelDirs)-1])
+		if l == 0 {
+			break
+		}
+		res = append(res, l)
+		levelDirs = append(levelDirs, next)
+	}
+	for len(res) > 0 && !hasModuleDir(levelDirs[len(res)]) {
+		res = res[:len(res)-1]
+	}
+	return res
+}
+
+// hexShardLevel returns the length of the hex names of the directories in
+// dir and the first of them, or 0 when dir holds spec files or other names.
+func hexShardLevel(dir string) (int, string) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, ""
+	}
+	l, next := 0, ""
+	for _, fi := range files {
+		if strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		if !fi.IsDir() {
+			if isSpecFileName(fi.Name()) {
+				return 0, ""
+			}
+			continue
+		}
+		if !regHexShard.MatchString(fi.Name()) || (l > 0 && len(fi.Name()) != l) {
+			return 0, ""
+		}
+		l = len(fi.Name())
+		if next == "" {
+			next = path.Join(dir, fi.Name())
+		}
+	}
+	return l, next
+}
+
+// hasModuleDir tells if the first directory in dir is a module, made of
+// version directories holding a spec file.
+func hasModuleDir(dir string) bool {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, fi := range files {
+		if !fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+			continue
+		}
+		versions, err := ioutil.ReadDir(path.Join(dir, fi.Name()))
+		if err != nil {
+			return false
+		}
+		for _, v := range versions {
+			if !v.IsDir() {
+				continue
+			}
+			specs, _ := ioutil.ReadDir(path.Join(dir, fi.Name(), v.Name()))
+			for _, spec := range specs {
+				if !spec.IsDir() && isSpecFileName(spec.Name()) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func isSpecFileName(name string) bool {
+	return strings.HasSuffix(name, ".podspec") || strings.HasSuffix(name, ".podspec.json")
+}
@@ -0,0 +1,146 @@
+package pod
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+// writeTestRepo writes files, keyed by their path in the repo, to a repo
+// named "test" and returns the directory holding it.
+func writeTestRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := path.Join(root, "test", name)
+		if err := os.MkdirAll(path.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func newTestPod(t *testing.T, files map[string]string) *Pod {
+	t.Helper()
+	aPod, err := PodIndex(writeTestRepo(t, files), []string{"test"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aPod
+}
+
+func TestNewPodRepoPrefixLengths(t *testing.T) {
+	spec := `{"name": "Foo", "version": "1.0"}`
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []int
+	}{
+		{
+			name:  "sharded",
+			files: map[string]string{"Specs/1/2/3/Foo/1.0/Foo.podspec.json": spec},
+			want:  []int{1, 1, 1},
+		},
+		{
+			name:  "flat",
+			files: map[string]string{"Foo/1.0/Foo.podspec.json": spec},
+		},
+		{
+			name:  "flat with a hex module name",
+			files: map[string]string{"Specs/abc/1.0/abc.podspec.json": spec},
+		},
+		{
+			name: "flat with hex module and version names",
+			files: map[string]string{
+				"Specs/ab/1/ab.podspec.json": spec,
+				"Specs/cd/2/cd.podspec":      "",
+			},
+		},
+		{
+			name:  "flat without Specs",
+			files: map[string]string{"abc/1.0/abc.podspec.json": spec},
+		},
+		{
+			name: "version file",
+			files: map[string]string{
+				"CocoaPods-version.yml":         "prefix_lengths:\n- 1\n- 1\n",
+				"Specs/a/b/Foo/1.0/Foo.podspec": "",
+			},
+			want: []int{1, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := writeTestRepo(t, tt.files)
+			repo, err := NewPodRepo("test", path.Join(root, "test"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(repo.PrefixLengths) != fmt.Sprint(tt.want) {
+				t.Errorf("PrefixLengths = %v, want %v", repo.PrefixLengths, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodRepoModuleDir(t *testing.T) {
+	// md5("Foo") is 1356c67d..., md5("Bar") is ddc35f88..., md5("Missing") is
+	// 2ae...
+	files := map[string]string{
+		"CocoaPods-version.yml":                       "prefix_lengths:\n- 1\n- 1\n- 1\n",
+		"Specs/1/3/5/Foo/1.0/Foo.podspec.json":        `{"name": "Foo", "version": "1.0"}`,
+		"Specs/1/3/5/Foo/1.1/Foo.podspec.json":        `{"name": "Foo", "version": "1.1"}`,
+		"Specs/d/d/c/Bar/2.0/Bar.podspec.json":        `{"name": "Bar", "version": "2.0"}`,
+		"Specs/d/d/c/.hidden/1.0/hidden.podspec.json": `{}`,
+	}
+	root := path.Join(writeTestRepo(t, files), "test")
+	repo, err := NewPodRepo("test", root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		want     string
+		versions int
+	}{
+		{name: "Foo", want: "Specs/1/3/5/Foo", versions: 2},
+		{name: "Foo/Core", want: "Specs/1/3/5/Foo", versions: 2},
+		{name: "Bar", want: "Specs/d/d/c/Bar", versions: 1},
+		{name: "Missing", want: "Specs/2/a/e/Missing"},
+	}
+	for _, tt := range tests {
+		if got := repo.ModuleDir(tt.name); got != path.Join(root, tt.want) {
+			t.Errorf("ModuleDir(%s) = %s, want %s", tt.name, got, tt.want)
+		}
+		aModule, err := repo.IndexModule(tt.name, nil)
+		if tt.versions == 0 {
+			if err == nil {
+				t.Errorf("IndexModule(%s) = %v, want an error", tt.name, aModule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(aModule.Versions) != tt.versions {
+			t.Errorf("IndexModule(%s) has %d versions, want %d", tt.name, len(aModule.Versions), tt.versions)
+		}
+	}
+
+	aPod, err := PodIndex(path.Dir(root), []string{"test"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, 2)
+	for _, aModule := range aPod.PodRepos[0].Modules {
+		names = append(names, aModule.Name)
+	}
+	if fmt.Sprint(names) != "[Foo Bar]" && fmt.Sprint(names) != "[Bar Foo]" {
+		t.Errorf("PodIndex() modules = %v, want Foo and Bar", names)
+	}
+}
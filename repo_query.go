@@ -0,0 +1,289 @@
+package pod
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	fdt "github.com/go-hayden-base/foundation"
+	ver "github.com/go-hayden-base/version"
+)
+
+// ** Pod Query Impl **
+
+// ModulesWithName returns the module in every repo that has it, in the order
+// of PodRepos, which is the priority order of the repos. Subspec names are
+// resolved to their root pod.
+func (s *Pod) ModulesWithName(name string) []*PodModule {
+	baseName := fdt.StrSplitFirst(name, "/")
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.moduleMap == nil {
+		s.moduleMap = make(map[string][]*PodModule)
+		for _, repo := range s.PodRepos {
+			for _, module := range repo.Modules {
+				s.moduleMap[module.Name] = append(s.moduleMap[module.Name], module)
+			}
+		}
+	}
+	return s.moduleMap[baseName]
+}
+
+// ModuleWithName returns the module from the repo with the highest priority.
+func (s *Pod) ModuleWithName(name string) *PodModule {
+	if modules := s.ModulesWithName(name); len(modules) > 0 {
+		return modules[0]
+	}
+	return nil
+}
+
+// ModuleVersions returns the versions of the module from all repos sorted
+// from the oldest to the newest. A version found in several repos is taken
+// from the repo with the highest priority.
+func (s *Pod) ModuleVersions(name string) []*PodModuleVersion {
+	modules := s.ModulesWithName(name)
+	dup := make(map[string]bool)
+	res := make([]*PodModuleVersion, 0, 10)
+	for _, module := range modules {
+		for _, version := range module.Versions {
+			if dup[version.Name] || !ver.IsVersion(version.Name) {
+				continue
+			}
+			dup[version.Name] = true
+			res = append(res, version)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return ver.CompareVersion(res[i].Name, res[j].Name) < 0
+	})
+	return res
+}
+
+func (s *Pod) Versions(name string) []string {
+	versions := s.ModuleVersions(name)
+	res := make([]string, 0, len(versions))
+	for _, version := range versions {
+		res = append(res, version.Name)
+	}
+	return res
+}
+
+// MatchVersion returns the newest version of the module that satisfies all
+// the constraints. Prerelease versions are only chosen when a constraint
+// names a prerelease.
+func (s *Pod) MatchVersion(name string, constraints []string) (string, bool) {
+	allowPre := false
+	for _, c := range constraints {
+		if strings.Contains(c, "-") {
+			allowPre = true
+			break
+		}
+	}
+	versions := s.Versions(name)
+	for i := len(versions) - 1; i > -1; i-- {
+		v := versions[i]
+		if !allowPre && isPrereleaseVersion(v) {
+			continue
+		}
+		if len(constraints) == 0 || ver.MatchVersionConstrains(constraints, v) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (s *Pod) ModuleVersion(name, version string) *PodModuleVersion {
+	for _, module := range s.ModulesWithName(name) {
+		if v := module.VersionWithName(version); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// RepoOfModuleVersion returns the repo with the highest priority that has
+// name@version.
+func (s *Pod) RepoOfModuleVersion(name, version string) *PodRepo {
+	baseName := fdt.StrSplitFirst(name, "/")
+	for _, repo := range s.PodRepos {
+		for _, module := range repo.Modules {
+			if module.Name == baseName && module.VersionWithName(version) != nil {
+				return repo
+			}
+		}
+	}
+	return nil
+}
+
+// Spec returns the parsed spec of name@version, reading it on first use.
+// For a subspec name the root spec is returned, see SubSpec. The spec is
+// hashed before it is returned, so callers may share it between goroutines.
+func (s *Pod) Spec(name, version string) (*Spec, error) {
+	v := s.ModuleVersion(name, version)
+	if v == nil {
+		return nil, errors.New("找不到 " + name + " (" + version + ")")
+	}
+	s.mutex.Lock()
+	aSpec, err := v.Podspec, v.Err
+	if aSpec != nil {
+		aSpec.HashSpec()
+	}
+	s.mutex.Unlock()
+	if aSpec != nil || err != nil {
+		return aSpec, err
+	}
+	aSpec, err = ReadSpecWithRunner(context.Background(), s.Runner, path.Join(v.Root, v.FileName))
+	if aSpec != nil {
+		aSpec.HashSpec()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if v.Podspec != nil || v.Err != nil {
+		if v.Podspec != nil {
+			v.Podspec.HashSpec()
+		}
+		return v.Podspec, v.Err
+	}
+	v.Podspec, v.Err = aSpec, err
+	return aSpec, err
+}
+
+// SubSpec resolves a path such as "Foo/Core/Utils" in the spec of version.
+func (s *Pod) SubSpec(name, version string) (*Spec, error) {
+	aSpec, err := s.Spec(name, version)
+	if err != nil {
+		return nil, err
+	}
+	aSubSpec := aSpec.SubspecWithPath(name)
+	if aSubSpec == nil {
+		return nil, errors.New(aSpec.Name + " (" + version + ") 中没有 " + name)
+	}
+	return aSubSpec, nil
+}
+
+func (s *Pod) resetModuleMap() {
+	s.mutex.Lock()
+	s.moduleMap = nil
+	s.mutex.Unlock()
+}
+
+// ** PodModule Query Impl **
+func (s *PodModule) VersionWithName(version string) *PodModuleVersion {
+	for _, v := range s.Versions {
+		if v.Name == version {
+			return v
+		}
+	}
+	return nil
+}
+
+// ** Func Public **
+
+// NewPodQueryVersionFunc answers version queries from the local repos of
+// aPod. It returns TagEmptyVersion when no version matches, so modules
+// missing from the repos do not stop MapPodfile.Evolution. Results are
+// memoized.
+func NewPodQueryVersionFunc(aPod *Pod) QueryVersionFunc {
+	var mutex sync.Mutex
+	memo := make(map[string]string)
+	return func(module string, constraints []string) (string, error) {
+		if aPod == nil {
+			return TagEmptyVersion, errors.New("Argement aPod is nil")
+		}
+		valid := make([]string, 0, len(constraints))
+		for _, c := range constraints {
+			if c != TagEmptyVersion && c != TagUnknownVersion && ver.IsVersionConstraint(c) {
+				valid = append(valid, c)
+			}
+		}
+		sort.Strings(valid)
+		key := module + "|" + strings.Join(valid, ",")
+		mutex.Lock()
+		v, ok := memo[key]
+		mutex.Unlock()
+		if ok {
+			return v, nil
+		}
+		v, _ = aPod.MatchVersion(module, valid)
+		mutex.Lock()
+		memo[key] = v
+		mutex.Unlock()
+		return v, nil
+	}
+}
+
+// NewPodQueryDependsFunc lists the dependencies of module@version from the
+// specs of aPod. For a subspec such as "Foo/Core" the dependencies of its
+// parents and of the subspecs it pulls in are included, dependencies inside
+// the pod itself are left out. Results are memoized.
+func NewPodQueryDependsFunc(aPod *Pod) QueryDependsFunc {
+	return NewPodQueryDependsFuncWithPlatform(aPod, SpecPlatformAny)
+}
+
+// NewPodQueryDependsFuncWithPlatform is NewPodQueryDependsFunc with the
+// dependencies scoped to platform, such as a Podfile target platform.
+func NewPodQueryDependsFuncWithPlatform(aPod *Pod, platform string) QueryDependsFunc {
+	type result struct {
+		depends []*DependBase
+		err     error
+	}
+	var mutex sync.Mutex
+	memo := make(map[string]*result)
+	return func(module, version string) ([]*DependBase, error) {
+		if aPod == nil {
+			return nil, errors.New("Argement aPod is nil")
+		}
+		key := module + "@" + version
+		mutex.Lock()
+		r, ok := memo[key]
+		mutex.Unlock()
+		if ok {
+			return r.depends, r.err
+		}
+		r = new(result)
+		r.depends, r.err = queryDependsFromPod(aPod, module, version, platform)
+		mutex.Lock()
+		memo[key] = r
+		mutex.Unlock()
+		return r.depends, r.err
+	}
+}
+
+// ** Func Private **
+func queryDependsFromPod(aPod *Pod, module, version, platform string) ([]*DependBase, error) {
+	if version == TagEmptyVersion || version == TagUnknownVersion {
+		return nil, errors.New(module + " 的版本未知")
+	}
+	aSpec, err := aPod.Spec(module, version)
+	if err != nil {
+		return nil, err
+	}
+	if aSpec.SubspecWithPath(module) == nil {
+		return nil, errors.New(aSpec.Name + " (" + version + ") 中没有 " + module)
+	}
+	rootPrefix := aSpec.Name + "/"
+	res := make([]*DependBase, 0, 5)
+	for name, versions := range aSpec.GetAllDependRequirements(module, platform) {
+		if name == aSpec.Name || strings.HasPrefix(name, rootPrefix) {
+			continue
+		}
+		if len(versions) == 0 {
+			res = append(res, &DependBase{N: name, V: TagEmptyVersion})
+			continue
+		}
+		for _, v := range versions {
+			res = append(res, &DependBase{N: name, V: v})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].N < res[j].N
+	})
+	return res, nil
+}
+
+func isPrereleaseVersion(v string) bool {
+	return strings.Contains(v, "-")
+}
@@ -0,0 +1,265 @@
+package pod
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testFooSpec = `{
+  "name": "Foo",
+  "version": "1.0",
+  "dependencies": {"Bar": ["~> 1.0"]},
+  "subspecs": [
+    {"name": "Core", "dependencies": {"Baz": [">= 2.0"]}},
+    {"name": "Util", "dependencies": {"Foo/Core": [], "Qux": []}}
+  ]
+}`
+
+// testFooSpecRequirements gives Bar several requirements, in the root spec
+// and in a subspec.
+const testFooSpecRequirements = `{
+  "name": "Foo",
+  "version": "1.2",
+  "dependencies": {"Bar": [">= 1.0", "< 2.0"]},
+  "subspecs": [
+    {"name": "Core", "dependencies": {"Bar": ["!= 1.1", "< 2.0"]}}
+  ]
+}`
+
+func newTestQueryPod(t *testing.T) *Pod {
+	t.Helper()
+	return newTestPod(t, map[string]string{
+		"Specs/Foo/1.0/Foo.podspec.json":      testFooSpec,
+		"Specs/Foo/1.10/Foo.podspec.json":     `{"name": "Foo", "version": "1.10"}`,
+		"Specs/Foo/1.2/Foo.podspec.json":      testFooSpecRequirements,
+		"Specs/Foo/2.0-beta/Foo.podspec.json": `{"name": "Foo", "version": "2.0-beta"}`,
+		"Specs/Bar/1.0/Bar.podspec.json":      `{"name": "Bar", "version": "1.0"}`,
+	})
+}
+
+func TestPodModuleWithName(t *testing.T) {
+	aPod := newTestQueryPod(t)
+	tests := []struct {
+		name     string
+		module   string
+		versions string
+	}{
+		{name: "Foo", module: "Foo", versions: "1.0,1.2,1.10,2.0-beta"},
+		{name: "Foo/Core", module: "Foo", versions: "1.0,1.2,1.10,2.0-beta"},
+		{name: "Foo/Core/Utils", module: "Foo", versions: "1.0,1.2,1.10,2.0-beta"},
+		{name: "Bar", module: "Bar", versions: "1.0"},
+		{name: "Missing"},
+		{name: "Missing/Core"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			module := aPod.ModuleWithName(tt.name)
+			if tt.module == "" {
+				if module != nil {
+					t.Errorf("ModuleWithName(%q) = %s, want nil", tt.name, module.Name)
+				}
+			} else if module == nil || module.Name != tt.module {
+				t.Errorf("ModuleWithName(%q) = %v, want %s", tt.name, module, tt.module)
+			}
+			if got := strings.Join(aPod.Versions(tt.name), ","); got != tt.versions {
+				t.Errorf("Versions(%q) = %q, want %q", tt.name, got, tt.versions)
+			}
+		})
+	}
+}
+
+func TestPodMatchVersion(t *testing.T) {
+	aPod := newTestQueryPod(t)
+	tests := []struct {
+		name        string
+		module      string
+		constraints []string
+		want        string
+	}{
+		{name: "newest release", module: "Foo", want: "1.10"},
+		{name: "subspec name", module: "Foo/Core", constraints: []string{"< 1.10"}, want: "1.2"},
+		{name: "all constraints", module: "Foo", constraints: []string{">= 1.0", "< 1.2"}, want: "1.0"},
+		{name: "prerelease named", module: "Foo", constraints: []string{">= 2.0-alpha"}, want: "2.0-beta"},
+		{name: "prerelease not named", module: "Foo", constraints: []string{">= 1.5"}, want: "1.10"},
+		{name: "no match", module: "Foo", constraints: []string{">= 3.0"}},
+		{name: "missing module", module: "Missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := aPod.MatchVersion(tt.module, tt.constraints)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("MatchVersion(%q, %v) = %q, %v, want %q", tt.module, tt.constraints, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestPodSubSpec(t *testing.T) {
+	aPod := newTestQueryPod(t)
+	tests := []struct {
+		name    string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{name: "Foo", version: "1.0", want: "Foo"},
+		{name: "Foo/Core", version: "1.0", want: "Core"},
+		{name: "Foo/Util", version: "1.0", want: "Util"},
+		{name: "Foo/Missing", version: "1.0", wantErr: true},
+		{name: "Foo", version: "9.9", wantErr: true},
+		{name: "Missing", version: "1.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+"@"+tt.version, func(t *testing.T) {
+			aSpec, err := aPod.SubSpec(tt.name, tt.version)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SubSpec(%q, %q) err = nil", tt.name, tt.version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if aSpec.Name != tt.want {
+				t.Errorf("SubSpec(%q, %q).Name = %q, want %q", tt.name, tt.version, aSpec.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPodQueryVersionFunc(t *testing.T) {
+	query := NewPodQueryVersionFunc(newTestQueryPod(t))
+	tests := []struct {
+		name        string
+		module      string
+		constraints []string
+		want        string
+	}{
+		{name: "no constraints", module: "Foo", want: "1.10"},
+		{name: "placeholders ignored", module: "Foo", constraints: []string{TagEmptyVersion, TagUnknownVersion, "< 1.10"}, want: "1.2"},
+		{name: "subspec", module: "Foo/Core", constraints: []string{"~> 1.0"}, want: "1.10"},
+		{name: "no match", module: "Foo", constraints: []string{">= 3.0"}, want: ""},
+		{name: "missing module", module: "Missing", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := query(tt.module, tt.constraints)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("query(%q, %v) = %q, want %q", tt.module, tt.constraints, got, tt.want)
+			}
+		})
+	}
+	if _, err := NewPodQueryVersionFunc(nil)("Foo", nil); err == nil {
+		t.Error("query on nil Pod err = nil")
+	}
+}
+
+func TestNewPodQueryDependsFunc(t *testing.T) {
+	query := NewPodQueryDependsFunc(newTestQueryPod(t))
+	tests := []struct {
+		module  string
+		version string
+		want    string
+		wantErr bool
+	}{
+		{module: "Foo", version: "1.0", want: "Bar (~> 1.0), Baz (>= 2.0), Qux ()"},
+		{module: "Foo/Core", version: "1.0", want: "Bar (~> 1.0), Baz (>= 2.0)"},
+		{module: "Foo/Util", version: "1.0", want: "Bar (~> 1.0), Baz (>= 2.0), Qux ()"},
+		{module: "Foo", version: "1.2", want: "Bar (>= 1.0), Bar (< 2.0), Bar (!= 1.1)"},
+		{module: "Foo/Core", version: "1.2", want: "Bar (>= 1.0), Bar (< 2.0), Bar (!= 1.1)"},
+		{module: "Bar", version: "1.0", want: ""},
+		{module: "Foo/Missing", version: "1.0", wantErr: true},
+		{module: "Foo", version: "9.9", wantErr: true},
+		{module: "Foo", version: TagEmptyVersion, wantErr: true},
+		{module: "Missing", version: "1.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.module+"@"+tt.version, func(t *testing.T) {
+			got, err := query(tt.module, tt.version)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("query(%q, %q) err = nil", tt.module, tt.version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s := dependString(got); s != tt.want {
+				t.Errorf("query(%q, %q) = %s, want %s", tt.module, tt.version, s, tt.want)
+			}
+		})
+	}
+}
+
+// testFooSpecWithPadding adds subspecs to testFooSpec, so that hashing it
+// takes long enough for concurrent queries to overlap.
+func testFooSpecWithPadding(n int) string {
+	padding := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		padding = append(padding, fmt.Sprintf(`{"name": "Pad%d"}`, i))
+	}
+	return strings.Replace(testFooSpec, `"subspecs": [`, `"subspecs": [`+strings.Join(padding, ",")+",", 1)
+}
+
+func TestPodQueryDependsConcurrent(t *testing.T) {
+	spec := testFooSpecWithPadding(200)
+	for round := 0; round < 5; round++ {
+		aPod := newTestPod(t, map[string]string{"Foo/1.0/Foo.podspec.json": spec})
+		// Load the spec first, so all the queries share it
+		if _, err := aPod.Spec("Foo", "1.0"); err != nil {
+			t.Fatal(err)
+		}
+		query := NewPodQueryDependsFunc(aPod)
+		modules := []string{"Foo/Core", "Foo/Util", "Foo", "Foo/Core", "Foo/Util"}
+		results := make([][]*DependBase, len(modules))
+		errs := make([]error, len(modules))
+		start := make(chan bool)
+		var wg sync.WaitGroup
+		for idx, module := range modules {
+			wg.Add(1)
+			go func(idx int, module string) {
+				defer wg.Done()
+				<-start
+				results[idx], errs[idx] = query(module, "1.0")
+			}(idx, module)
+		}
+		close(start)
+		wg.Wait()
+		for idx, module := range modules {
+			if errs[idx] != nil {
+				t.Fatalf("%s: %v", module, errs[idx])
+			}
+		}
+		if got := dependNames(results[0]); got != "Bar,Baz" {
+			t.Errorf("Foo/Core depends on %s", got)
+		}
+		if got := dependNames(results[1]); got != "Bar,Baz,Qux" {
+			t.Errorf("Foo/Util depends on %s", got)
+		}
+		aSpec, err := aPod.Spec("Foo", "1.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if aSpec.ModulePath != "Foo" || aSpec.SubspecWithPath("Foo/Core").ModulePath != "Foo/Core" {
+			t.Errorf("module paths %q %q", aSpec.ModulePath, aSpec.SubspecWithPath("Foo/Core").ModulePath)
+		}
+	}
+}
+
+func dependNames(depends []*DependBase) string {
+	res := ""
+	for idx, d := range depends {
+		if idx > 0 {
+			res += ","
+		}
+		res += d.N
+	}
+	return res
+}
@@ -1,8 +1,18 @@
 package pod
 
+import (
+	"encoding/json"
+	"sync"
+)
+
 // Type Define
 type Pod struct {
 	PodRepos []*PodRepo
+	// Runner converts .podspec files in ResolvePodSpecs, nil means DefaultCommandRunner
+	Runner CommandRunner
+
+	moduleMap map[string][]*PodModule
+	mutex     sync.Mutex
 }
 
 type PodBase struct {
@@ -10,9 +20,14 @@ type PodBase struct {
 	Root string
 }
 
+// PodRepo is a spec repo. Root is the directory holding the modules, the
+// Specs directory when the repo has one. PrefixLengths is the sharding of
+// the repo as declared in CocoaPods-version.yml, e.g. [1, 1, 1] for the
+// master repo where Foo lives in Specs/a/b/c/Foo, and nil for a flat repo.
 type PodRepo struct {
 	PodBase
-	Modules []*PodModule
+	PrefixLengths []int
+	Modules       []*PodModule
 }
 
 type PodModule struct {
@@ -27,3 +42,35 @@ type PodModuleVersion struct {
 	Podspec  *Spec
 	Err      error
 }
+
+// *** Private ***
+type p_repo_version struct {
+	Prefix_lengths []int
+}
+
+// PodIndexChanges lists the modules of a repo that were added, updated or
+// removed since the cache was last written.
+type PodIndexChanges struct {
+	Added   []string
+	Updated []string
+	Removed []string
+}
+
+type p_index_cache struct {
+	Version int
+	Root    string
+	Modules map[string]*p_cache_module
+}
+
+type p_cache_module struct {
+	ModTime  int64
+	Versions []*p_cache_version
+}
+
+type p_cache_version struct {
+	Name     string
+	FileName string
+	Size     int64
+	ModTime  int64
+	Spec     json.RawMessage `json:",omitempty"`
+}
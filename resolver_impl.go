@@ -0,0 +1,314 @@
+package pod
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	fdt "github.com/go-hayden-base/foundation"
+	ver "github.com/go-hayden-base/version"
+)
+
+const defaultResolverMaxSteps = 10000
+
+func NewResolver(qvFunc QueryVersionFunc, qdFunc QueryDependsFunc) *Resolver {
+	aResolver := new(Resolver)
+	aResolver.queryVersionFunc = qvFunc
+	aResolver.queryDependsFunc = qdFunc
+	return aResolver
+}
+
+// ** Resolver Impl **
+
+// Resolve finds versions for depends, whose V are Podfile requirements.
+func (s *Resolver) Resolve(depends []*DependBase) (*Resolution, error) {
+	return s.resolve(depends, nil)
+}
+
+// ResolvePodfileTarget resolves the effective modules of aTarget, with a
+// constraint per version requirement. Local modules keep their version and
+// the Depends filled by Podfile.FillLocalModuleDepends.
+func (s *Resolver) ResolvePodfileTarget(aTarget *PodfileTarget) (*Resolution, error) {
+	if aTarget == nil {
+		return nil, errors.New("Argement aTarget is nil")
+	}
+	depends := make([]*DependBase, 0, len(aTarget.Modules))
+	locals := make([]*PodfileModule, 0, 2)
+	for _, aModule := range aTarget.EffectiveModules() {
+		if aModule.IsLocal() {
+			locals = append(locals, aModule)
+			continue
+		}
+		requirements := aModule.VersionRequirements()
+		if len(requirements) == 0 {
+			depends = append(depends, &DependBase{N: aModule.N})
+		}
+		for _, r := range requirements {
+			depends = append(depends, &DependBase{N: aModule.N, V: r})
+		}
+	}
+	return s.resolve(depends, locals)
+}
+
+func (s *Resolver) resolve(depends []*DependBase, locals []*PodfileModule) (*Resolution, error) {
+	if s.queryVersionFunc == nil || s.queryDependsFunc == nil {
+		return nil, errors.New("Resolver 需要 QueryVersionFunc 和 QueryDependsFunc")
+	}
+	state := newResolveState()
+	for _, aLocal := range locals {
+		root := fdt.StrSplitFirst(aLocal.N, "/")
+		state.require(aLocal.N)
+		state.assigned[root] = aLocal.V
+		state.depends[aLocal.N] = aLocal.Depends
+	}
+	for _, aLocal := range locals {
+		for _, aDepend := range aLocal.Depends {
+			if incompat := state.addConstraint(aDepend, aLocal.N, aLocal.V); incompat != nil {
+				return nil, &ResolveError{Chain: []*ResolveIncompatibility{incompat}}
+			}
+		}
+	}
+	for _, aDepend := range depends {
+		if incompat := state.addConstraint(aDepend, "", ""); incompat != nil {
+			return nil, &ResolveError{Chain: []*ResolveIncompatibility{incompat}}
+		}
+	}
+	steps := 0
+	res, rErr := s.search(state, &steps)
+	if rErr != nil {
+		return nil, rErr
+	}
+	return res.resolution(), nil
+}
+
+// search decides the next pod, steps counts the decisions of the current
+// resolve.
+func (s *Resolver) search(state *resolveState, steps *int) (*resolveState, *ResolveError) {
+	*steps++
+	maxSteps := s.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = defaultResolverMaxSteps
+	}
+	if *steps > maxSteps {
+		return nil, &ResolveError{Err: errors.New("超过最大尝试次数 " + strconv.Itoa(maxSteps))}
+	}
+	root, ok := state.nextUndecided()
+	if !ok {
+		return state, nil
+	}
+	constraints := state.constraintStrings(root)
+	var lastErr *ResolveError
+	tried := make([]string, 0, 2)
+	for {
+		query := constraints
+		if len(tried) > 0 {
+			query = append(append([]string{}, constraints...), "< "+tried[len(tried)-1])
+		}
+		v, err := s.queryVersionFunc(root, query)
+		if err != nil {
+			return nil, &ResolveError{Err: err}
+		}
+		if v == TagEmptyVersion || v == TagUnknownVersion || fdt.SliceContainsStr(v, tried) {
+			break
+		}
+		tried = append(tried, v)
+		next := state.clone()
+		next.assigned[root] = v
+		if rErr := s.applyDepends(next, root); rErr != nil {
+			if rErr.Err != nil {
+				return nil, rErr
+			}
+			lastErr = rErr
+			continue
+		}
+		res, rErr := s.search(next, steps)
+		if rErr == nil {
+			return res, nil
+		}
+		if rErr.Err != nil {
+			return nil, rErr
+		}
+		lastErr = rErr
+	}
+	if lastErr == nil {
+		// No version at all matches the constraints on root
+		return nil, &ResolveError{Chain: []*ResolveIncompatibility{{Module: root, Causes: state.constraints[root]}}}
+	}
+	for _, v := range tried {
+		lastErr.Tried = append(lastErr.Tried, root+" "+v)
+	}
+	return nil, lastErr
+}
+
+// applyDepends adds the dependencies of every required module of root.
+func (s *Resolver) applyDepends(state *resolveState, root string) *ResolveError {
+	v := state.assigned[root]
+	for i := 0; i < len(state.required[root]); i++ {
+		name := state.required[root][i]
+		if _, ok := state.depends[name]; ok {
+			continue
+		}
+		depends, err := s.queryDependsFunc(name, v)
+		if err != nil {
+			return &ResolveError{Err: err}
+		}
+		state.depends[name] = depends
+		for _, aDepend := range depends {
+			incompat := state.addConstraint(aDepend, name, v)
+			if incompat != nil {
+				return &ResolveError{Chain: []*ResolveIncompatibility{incompat}}
+			}
+			if dependRoot := fdt.StrSplitFirst(aDepend.N, "/"); dependRoot != root {
+				if _, ok := state.assigned[dependRoot]; ok {
+					if rErr := s.applyDepends(state, dependRoot); rErr != nil {
+						return rErr
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ** resolveState Impl **
+func newResolveState() *resolveState {
+	state := new(resolveState)
+	state.assigned = make(map[string]string)
+	state.required = make(map[string][]string)
+	state.constraints = make(map[string][]*ResolveConstraint)
+	state.depends = make(map[string][]*DependBase)
+	return state
+}
+
+func (s *resolveState) clone() *resolveState {
+	c := newResolveState()
+	c.order = append(c.order, s.order...)
+	for k, v := range s.assigned {
+		c.assigned[k] = v
+	}
+	for k, v := range s.required {
+		c.required[k] = append([]string(nil), v...)
+	}
+	for k, v := range s.constraints {
+		c.constraints[k] = append([]*ResolveConstraint(nil), v...)
+	}
+	for k, v := range s.depends {
+		c.depends[k] = v
+	}
+	return c
+}
+
+func (s *resolveState) require(name string) {
+	root := fdt.StrSplitFirst(name, "/")
+	names, ok := s.required[root]
+	if !ok {
+		s.order = append(s.order, root)
+	}
+	if !fdt.SliceContainsStr(name, names) {
+		s.required[root] = append(names, name)
+	}
+}
+
+// addConstraint requires aDepend and returns an incompatibility when its
+// root is already decided on a version that does not match.
+func (s *resolveState) addConstraint(aDepend *DependBase, by, byVersion string) *ResolveIncompatibility {
+	root := fdt.StrSplitFirst(aDepend.N, "/")
+	if by != "" && fdt.StrSplitFirst(by, "/") == root {
+		s.require(aDepend.N)
+		return nil
+	}
+	s.require(aDepend.N)
+	c := aDepend.V
+	if c == TagEmptyVersion || c == TagUnknownVersion || !ver.IsVersionConstraint(c) {
+		return nil
+	}
+	aConstraint := &ResolveConstraint{Module: root, Constraint: c, By: by, ByVersion: byVersion}
+	s.constraints[root] = append(s.constraints[root], aConstraint)
+	if v, ok := s.assigned[root]; ok && !ver.MatchVersionConstraint(c, v) {
+		return &ResolveIncompatibility{Module: root, Chosen: v, Causes: s.constraints[root]}
+	}
+	return nil
+}
+
+func (s *resolveState) nextUndecided() (string, bool) {
+	for _, root := range s.order {
+		if _, ok := s.assigned[root]; !ok {
+			return root, true
+		}
+	}
+	return "", false
+}
+
+func (s *resolveState) constraintStrings(root string) []string {
+	res := make([]string, 0, len(s.constraints[root]))
+	for _, c := range s.constraints[root] {
+		if !fdt.SliceContainsStr(c.Constraint, res) {
+			res = append(res, c.Constraint)
+		}
+	}
+	return res
+}
+
+func (s *resolveState) resolution() *Resolution {
+	res := new(Resolution)
+	res.Versions = make(map[string]string, len(s.assigned))
+	for k, v := range s.assigned {
+		res.Versions[k] = v
+	}
+	res.Depends = make(map[string][]*DependBase, len(s.depends))
+	for _, root := range s.order {
+		for _, name := range s.required[root] {
+			res.Modules = append(res.Modules, name)
+			res.Depends[name] = s.depends[name]
+		}
+	}
+	sort.Strings(res.Modules)
+	return res
+}
+
+// ** Resolution Impl **
+func (s *Resolution) VersionOfModule(name string) (string, bool) {
+	v, ok := s.Versions[fdt.StrSplitFirst(name, "/")]
+	return v, ok
+}
+
+// ** ResolveConstraint Impl **
+func (s *ResolveConstraint) String() string {
+	by := "Podfile"
+	if s.By != "" {
+		by = s.By + " " + s.ByVersion
+	}
+	return by + " needs " + s.Module + " " + s.Constraint
+}
+
+// ** ResolveIncompatibility Impl **
+func (s *ResolveIncompatibility) String() string {
+	causes := make([]string, 0, len(s.Causes))
+	for _, c := range s.Causes {
+		causes = append(causes, c.String())
+	}
+	msg := strings.Join(causes, ", but ")
+	if s.Chosen != "" {
+		return msg + " (" + s.Module + " " + s.Chosen + " was chosen)"
+	}
+	if len(causes) < 2 {
+		return msg + ", but no version of " + s.Module + " matches"
+	}
+	return msg
+}
+
+// ** ResolveError Impl **
+func (s *ResolveError) Error() string {
+	if s.Err != nil {
+		return "依赖解析失败: " + s.Err.Error()
+	}
+	lines := make([]string, 0, len(s.Chain)+1)
+	for _, incompat := range s.Chain {
+		lines = append(lines, incompat.String())
+	}
+	if len(s.Tried) > 0 {
+		lines = append(lines, "tried "+strings.Join(s.Tried, ", "))
+	}
+	return "依赖冲突: " + strings.Join(lines, "; ")
+}
@@ -0,0 +1,218 @@
+package pod
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	ver "github.com/go-hayden-base/version"
+)
+
+// testIndex maps "name version" to the dependencies of that version, given
+// as "name constraint" strings.
+type testIndex map[string][]string
+
+func (s testIndex) queryVersion(module string, constraints []string) (string, error) {
+	root := strings.SplitN(module, "/", 2)[0]
+	best := TagEmptyVersion
+	for key := range s {
+		name, v := splitTestKey(key)
+		if name != root || !ver.MatchVersionConstrains(constraints, v) {
+			continue
+		}
+		if best == TagEmptyVersion || ver.CompareVersion(v, best) > 0 {
+			best = v
+		}
+	}
+	return best, nil
+}
+
+func (s testIndex) queryDepends(module, version string) ([]*DependBase, error) {
+	depends, ok := s[strings.SplitN(module, "/", 2)[0]+" "+version]
+	if !ok {
+		return nil, errors.New("no spec for " + module + " " + version)
+	}
+	res := make([]*DependBase, 0, len(depends))
+	for _, d := range depends {
+		name, c := splitTestKey(d)
+		res = append(res, &DependBase{N: name, V: c})
+	}
+	return res, nil
+}
+
+func splitTestKey(key string) (string, string) {
+	parts := strings.SplitN(key, " ", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+func testDepends(items ...string) []*DependBase {
+	res := make([]*DependBase, 0, len(items))
+	for _, item := range items {
+		name, c := splitTestKey(item)
+		res = append(res, &DependBase{N: name, V: c})
+	}
+	return res
+}
+
+func TestResolverResolve(t *testing.T) {
+	index := testIndex{
+		"A 1.0": {"C >= 1.0"},
+		"A 2.0": {"C >= 2.0"},
+		"B 1.0": {"C < 2.0"},
+		"C 1.0": nil,
+		"C 1.5": nil,
+		"C 2.0": nil,
+		"D 1.0": {"E ~> 1.0"},
+		"E 2.0": nil,
+	}
+	tests := []struct {
+		name     string
+		depends  []*DependBase
+		want     map[string]string
+		wantErrs []string
+	}{
+		{
+			name:    "newest versions",
+			depends: testDepends("A", "C"),
+			want:    map[string]string{"A": "2.0", "C": "2.0"},
+		},
+		{
+			name:    "backtracks to an older version",
+			depends: testDepends("A", "B"),
+			want:    map[string]string{"A": "1.0", "B": "1.0", "C": "1.5"},
+		},
+		{
+			name:    "conflict between the Podfile and a dependency",
+			depends: testDepends("B", "C >= 2.0"),
+			wantErrs: []string{
+				"Podfile needs C >= 2.0, but B 1.0 needs C < 2.0",
+				"tried B 1.0",
+			},
+		},
+		{
+			name:     "no version matches",
+			depends:  testDepends("D"),
+			wantErrs: []string{"D 1.0 needs E ~> 1.0, but no version of E matches", "tried D 1.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := NewResolver(index.queryVersion, index.queryDepends).Resolve(tt.depends)
+			if len(tt.wantErrs) > 0 {
+				if err == nil {
+					t.Fatalf("Resolve() = %v, want an error", res.Versions)
+				}
+				for _, want := range tt.wantErrs {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("Resolve() error = %q, want it to contain %q", err.Error(), want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, v := range tt.want {
+				if res.Versions[name] != v {
+					t.Errorf("Versions[%s] = %q, want %q", name, res.Versions[name], v)
+				}
+			}
+			if len(res.Versions) != len(tt.want) {
+				t.Errorf("Versions = %v, want %v", res.Versions, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolverQueryDependsError(t *testing.T) {
+	index := testIndex{"A 1.0": {"B"}, "B 1.0": nil}
+	failing := func(module, version string) ([]*DependBase, error) {
+		if module == "B" {
+			return nil, errors.New("spec of B is unreadable")
+		}
+		return index.queryDepends(module, version)
+	}
+	_, err := NewResolver(index.queryVersion, failing).Resolve(testDepends("A"))
+	rErr, ok := err.(*ResolveError)
+	if !ok || rErr.Err == nil || !strings.Contains(rErr.Err.Error(), "unreadable") {
+		t.Fatalf("Resolve() error = %v, want the query error", err)
+	}
+}
+
+func TestResolverPodfileTargetRequirements(t *testing.T) {
+	index := testIndex{"A 1.0": nil, "A 1.5": nil, "A 2.0": nil}
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte("target 'App' do\n  pod 'A', '>= 1.0', '< 2.0'\nend\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := NewResolver(index.queryVersion, index.queryDepends).ResolvePodfileTarget(aPodfile.TargetWithName("App"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := res.Versions["A"]; v != "1.5" {
+		t.Errorf("Versions[A] = %q, want 1.5", v)
+	}
+}
+
+func TestResolverConcurrentSteps(t *testing.T) {
+	index := testIndex{
+		"A 1.0": {"C >= 1.0"},
+		"A 2.0": {"C >= 2.0"},
+		"B 1.0": {"C < 2.0"},
+		"C 1.0": nil,
+		"C 2.0": nil,
+	}
+	aResolver := NewResolver(index.queryVersion, index.queryDepends)
+	// Each resolve backtracks once and takes 7 steps, shared steps would
+	// exceed MaxSteps
+	aResolver.MaxSteps = 7
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for idx := range errs {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			_, errs[idx] = aResolver.Resolve(testDepends("A", "B"))
+		}(idx)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestResolverPodfileTargetLocal(t *testing.T) {
+	index := testIndex{
+		"A 1.0": {"C >= 1.0"},
+		"C 1.0": nil,
+		"C 1.5": nil,
+		"C 2.0": nil,
+	}
+	aPodfile, err := NewPodfileWithBytes("Podfile", []byte("target 'App' do\n  pod 'A', '~> 1.0'\n  pod 'L', :path => '../L'\nend\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aTarget := aPodfile.TargetWithName("App")
+	for _, aModule := range aTarget.Modules {
+		if aModule.N == "L" {
+			aModule.V = "0.1"
+			aModule.Depends = testDepends("C < 2.0")
+		}
+	}
+	res, err := NewResolver(index.queryVersion, index.queryDepends).ResolvePodfileTarget(aTarget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"A": "1.0", "C": "1.5", "L": "0.1"}
+	for name, v := range want {
+		if res.Versions[name] != v {
+			t.Errorf("Versions[%s] = %q, want %q", name, res.Versions[name], v)
+		}
+	}
+}
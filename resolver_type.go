@@ -0,0 +1,56 @@
+package pod
+
+// Resolver picks one version per pod with backtracking: when the version
+// chosen for a pod leads to a conflict, older versions are tried before
+// giving up. MaxSteps bounds the number of decisions of each resolve, 0
+// means 10000. A Resolver may resolve concurrently when its query funcs are
+// safe for concurrent use.
+type Resolver struct {
+	MaxSteps int
+
+	queryVersionFunc QueryVersionFunc
+	queryDependsFunc QueryDependsFunc
+}
+
+// Resolution maps each root pod to its version, Depends holds the
+// dependencies of every required module (pods and subspecs).
+type Resolution struct {
+	Versions map[string]string
+	Modules  []string
+	Depends  map[string][]*DependBase
+}
+
+// ResolveConstraint is a requirement on Module added by By at ByVersion, By
+// is empty for a requirement of the Podfile.
+type ResolveConstraint struct {
+	Module     string
+	Constraint string
+	By         string
+	ByVersion  string
+}
+
+// ResolveIncompatibility is a set of constraints on Module that no version
+// satisfies, or that the version chosen for Module (Chosen) does not.
+type ResolveIncompatibility struct {
+	Module string
+	Chosen string
+	Causes []*ResolveConstraint
+}
+
+// ResolveError explains why no consistent assignment exists. Chain starts
+// with the root incompatibility, Tried lists the versions that were given up
+// while backtracking, e.g. "A 2.1".
+type ResolveError struct {
+	Chain []*ResolveIncompatibility
+	Tried []string
+	Err   error
+}
+
+// *** Private ***
+type resolveState struct {
+	order       []string
+	assigned    map[string]string
+	required    map[string][]string
+	constraints map[string][]*ResolveConstraint
+	depends     map[string][]*DependBase
+}
@@ -1,10 +1,10 @@
 package pod
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
-	"os/exec"
 	"path"
 	"regexp"
 	"strings"
@@ -26,6 +26,7 @@ func (s *Spec) HashSpec() {
 	}
 
 	if len(s.Subspecs) == 0 {
+		s.hasHash = true
 		return
 	}
 
@@ -51,22 +52,77 @@ func (s *Spec) JSON() ([]byte, error) {
 	return json.Marshal(s)
 }
 
-func (s *Spec) enumerateDepends(f func(module, depend, version string)) {
+func (s *Spec) enumerateDepends(platform string, f func(module, depend, version string)) {
 	if f == nil {
 		return
 	}
-	if s.Dependences != nil {
-		s.Dependences.enumerateDepends(func(dep, ver string) {
+	if depends := s.ownDepends(platform); depends != nil {
+		depends.enumerateDepends(func(dep, ver string) {
 			f(s.Name, dep, ver)
 		})
 	}
 	if s.Subspecs != nil {
 		for _, spec := range s.Subspecs {
-			spec.enumerateDepends(f)
+			spec.enumerateDepends(platform, f)
 		}
 	}
 }
 
+// PlatformSpec returns the attributes scoped to platform, such as the
+// "ios" block of a spec, nil when there are none.
+func (s *Spec) PlatformSpec(platform string) *Spec {
+	switch normalizeSpecPlatform(platform) {
+	case SpecPlatformIOS:
+		return s.IOS
+	case SpecPlatformOSX:
+		return s.OSX
+	case SpecPlatformTVOS:
+		return s.TVOS
+	case SpecPlatformWatchOS:
+		return s.WatchOS
+	}
+	return nil
+}
+
+// SupportsPlatform tells if the spec declares platform, a spec without
+// platforms supports all of them.
+func (s *Spec) SupportsPlatform(platform string) bool {
+	if s.Platforms == nil || platform == SpecPlatformAny {
+		return true
+	}
+	_, ok := s.Platforms.Version(platform)
+	return ok
+}
+
+// ownDepends merges the dependencies of the spec itself with the ones
+// scoped to platform, SpecPlatformAny takes every platform.
+func (s *Spec) ownDepends(platform string) SpecDenpendence {
+	platforms := []string{platform}
+	if platform == SpecPlatformAny {
+		platforms = SpecPlatforms
+	}
+	var res SpecDenpendence
+	for _, p := range platforms {
+		aPlatformSpec := s.PlatformSpec(p)
+		if aPlatformSpec == nil || len(aPlatformSpec.Dependences) == 0 {
+			continue
+		}
+		if res == nil {
+			res = make(SpecDenpendence, len(s.Dependences)+len(aPlatformSpec.Dependences))
+			for name, versions := range s.Dependences {
+				res[name] = versions
+			}
+		}
+		for name, versions := range aPlatformSpec.Dependences {
+			res[name] = versions
+		}
+	}
+	if res == nil {
+		return s.Dependences
+	}
+	return res
+}
+
 func (s *Spec) IsDefaultSpec(name string) bool {
 	if s.DefaultSpecs == nil {
 		return true
@@ -84,7 +140,24 @@ func (s *Spec) IsDefaultSpec(name string) bool {
 	return false
 }
 
-func (s *Spec) GetAllDepends(name string) map[string]string {
+// GetAllDepends returns the dependencies of the spec at name on platform,
+// including the ones of the subspecs it pulls in, with their first
+// requirement.
+func (s *Spec) GetAllDepends(name, platform string) map[string]string {
+	requirements := s.GetAllDependRequirements(name, platform)
+	if requirements == nil {
+		return nil
+	}
+	res := make(map[string]string, len(requirements))
+	for key := range requirements {
+		res[key] = requirements.Version(key)
+	}
+	return res
+}
+
+// GetAllDependRequirements is GetAllDepends with every requirement of the
+// dependencies.
+func (s *Spec) GetAllDependRequirements(name, platform string) SpecDenpendence {
 	s.HashSpec()
 	baseName := fdt.StrSplitFirst(name, "/")
 	if baseName == "" || baseName != s.Name {
@@ -92,14 +165,14 @@ func (s *Spec) GetAllDepends(name string) map[string]string {
 	}
 	regString := `^` + baseName + `(/\S+)*$`
 	reg := regexp.MustCompile(regString)
-	res := make(map[string]string)
+	res := make(SpecDenpendence)
 	add := make(map[string]bool)
-	res[name] = ""
-	f := func(a map[string]string, check map[string]bool, r *regexp.Regexp) (string, bool) {
+	res[name] = nil
+	f := func(a SpecDenpendence, check map[string]bool, r *regexp.Regexp) (string, bool) {
 		if a == nil || check == nil {
 			return "", false
 		}
-		for key, _ := range a {
+		for key := range a {
 			if _, ok := check[key]; !ok && r.MatchString(key) {
 				return key, true
 			}
@@ -113,7 +186,7 @@ func (s *Spec) GetAllDepends(name string) map[string]string {
 			break
 		}
 		if specs := s.getPathSubspecs(p); specs != nil && len(specs) > 0 {
-			mergeDpendMap(res, getPathDepends(specs))
+			mergeDependRequirements(res, getPathDependRequirements(specs, platform))
 		}
 		add[p] = true
 	}
@@ -124,36 +197,70 @@ func (s *Spec) GetAllDepends(name string) map[string]string {
 	return nil
 }
 
-func (s *Spec) GetExcludeSubspecDepends() map[string]string {
+func (s *Spec) GetExcludeSubspecDepends(platform string) map[string]string {
 	s.HashSpec()
-	if s.Dependences == nil {
+	depends := s.ownDepends(platform)
+	if depends == nil {
 		return nil
 	}
 	res := make(map[string]string)
-	s.Dependences.enumerateDepends(func(d, v string) {
+	depends.enumerateDepends(func(d, v string) {
 		res[d] = v
 	})
 	return res
 }
 
-func (s *Spec) GetDepends() map[string]string {
+func (s *Spec) GetDepends(platform string) map[string]string {
 	s.HashSpec()
 	res := make(map[string]string)
-	mergeDpendMap(res, s.GetExcludeSubspecDepends())
+	mergeDpendMap(res, s.GetExcludeSubspecDepends(platform))
 	if s.DefaultSpecsMap != nil {
 		for _, spec := range s.DefaultSpecsMap {
 			res[spec.ModulePath] = ""
-			mergeDpendMap(res, spec.GetDepends())
+			mergeDpendMap(res, spec.GetDepends(platform))
 		}
 	} else {
 		for _, spec := range s.SingleSpecsMap {
 			res[spec.ModulePath] = ""
-			mergeDpendMap(res, spec.GetDepends())
+			mergeDpendMap(res, spec.GetDepends(platform))
 		}
 	}
 	return res
 }
 
+// dependRequirements is GetDepends with every requirement of the
+// dependencies.
+func (s *Spec) dependRequirements(platform string) SpecDenpendence {
+	s.HashSpec()
+	res := make(SpecDenpendence)
+	mergeDependRequirements(res, s.ownDepends(platform))
+	specs := s.DefaultSpecsMap
+	if specs == nil {
+		specs = s.SingleSpecsMap
+	}
+	for _, spec := range specs {
+		if _, ok := res[spec.ModulePath]; !ok {
+			res[spec.ModulePath] = nil
+		}
+		mergeDependRequirements(res, spec.dependRequirements(platform))
+	}
+	return res
+}
+
+// SubspecWithPath returns the spec at a path such as "Foo/Core", or the spec
+// itself for its own name.
+func (s *Spec) SubspecWithPath(name string) *Spec {
+	s.HashSpec()
+	if fdt.StrSplitFirst(name, "/") != s.Name {
+		return nil
+	}
+	specs := s.getPathSubspecs(name)
+	if len(specs) == 0 {
+		return nil
+	}
+	return specs[len(specs)-1]
+}
+
 func (s *Spec) getPathSubspecs(name string) []*Spec {
 	subs := strings.Split(name, "/")
 	l := len(subs)
@@ -214,24 +321,70 @@ func (s SpecDenpendence) Version(name string) string {
 	return ""
 }
 
+// ** SpecPlatform Impl **
+
+// Version returns the deployment target of platform and whether the
+// platform is declared, a platform declared without version gives "".
+func (s *SpecPlatform) Version(platform string) (string, bool) {
+	var v string
+	switch normalizeSpecPlatform(platform) {
+	case SpecPlatformIOS:
+		v = s.IOS
+	case SpecPlatformOSX:
+		v = s.OSX
+	case SpecPlatformTVOS:
+		v = s.TVOS
+	case SpecPlatformWatchOS:
+		v = s.WatchOS
+	default:
+		return "", false
+	}
+	return v, v != "" || s.declared[normalizeSpecPlatform(platform)]
+}
+
 // ** Private Func **
+
+// normalizeSpecPlatform maps the names used by Podfiles to the keys of a
+// podspec, "macos" is "osx" there.
+func normalizeSpecPlatform(platform string) string {
+	platform = strings.ToLower(strings.TrimPrefix(platform, ":"))
+	if platform == "macos" {
+		return SpecPlatformOSX
+	}
+	return platform
+}
+
 func mergeDpendMap(a map[string]string, b map[string]string) {
 	for key, val := range b {
 		a[key] = val
 	}
 }
 
-func getPathDepends(specs []*Spec) map[string]string {
+// mergeDependRequirements adds the requirements of b missing from a.
+func mergeDependRequirements(a, b SpecDenpendence) {
+	for key, versions := range b {
+		if _, ok := a[key]; !ok {
+			a[key] = nil
+		}
+		for _, v := range versions {
+			if !fdt.SliceContainsStr(v, a[key]) {
+				a[key] = append(a[key], v)
+			}
+		}
+	}
+}
+
+func getPathDependRequirements(specs []*Spec, platform string) SpecDenpendence {
 	l := len(specs)
 	if l == 0 {
 		return nil
 	}
-	res := make(map[string]string)
+	res := make(SpecDenpendence)
 	for idx, spec := range specs {
 		if idx == l-1 {
-			mergeDpendMap(res, spec.GetDepends())
+			mergeDependRequirements(res, spec.dependRequirements(platform))
 		} else {
-			mergeDpendMap(res, spec.GetExcludeSubspecDepends())
+			mergeDependRequirements(res, spec.ownDepends(platform))
 		}
 	}
 	return res
@@ -239,6 +392,13 @@ func getPathDepends(specs []*Spec) map[string]string {
 
 // ** Public Func **
 func ReadSpec(filePath string) (*Spec, error) {
+	return ReadSpecWithRunner(context.Background(), nil, filePath)
+}
+
+// ReadSpecWithRunner reads a .json spec directly and evaluates a .podspec
+// natively, falling back to `pod ipc spec` through runner when ParsePodspec
+// can not evaluate it. nil runner means DefaultCommandRunner.
+func ReadSpecWithRunner(ctx context.Context, runner CommandRunner, filePath string) (*Spec, error) {
 	if len(filePath) == 0 || !fs.FileExists(filePath) {
 		return nil, errors.New("请正确指定spec文件！")
 	}
@@ -254,7 +414,14 @@ func ReadSpec(filePath string) (*Spec, error) {
 			return nil, err
 		}
 	} else {
-		b, err = exec.Command("pod", "ipc", "spec", filePath).Output()
+		aSpec, err := ParsePodspec(filePath)
+		if err == nil {
+			return aSpec, nil
+		}
+		if _, ok := err.(*PodspecUnsupportedError); !ok {
+			return nil, err
+		}
+		b, err = commandRunnerOrDefault(runner).RunPod(ctx, "ipc", "spec", filePath)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,143 @@
+package pod
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const testPlatformSpec = `{
+  "name": "Foo",
+  "version": "1.0",
+  "platforms": {"ios": "9.0", "osx": "10.12", "tvos": null},
+  "dependencies": {"Common": []},
+  "ios": {"dependencies": {"UIKitExt": ["~> 1.0"]}},
+  "osx": {"dependencies": {"AppKitExt": []}},
+  "subspecs": [
+    {
+      "name": "Core",
+      "ios": {"dependencies": {"CoreIOS": []}},
+      "osx": {"dependencies": {"CoreMac": []}}
+    }
+  ]
+}`
+
+func testDependNames(depends map[string]string) string {
+	names := make([]string, 0, len(depends))
+	for name := range depends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func TestSpecGetAllDependsPlatform(t *testing.T) {
+	aSpec, err := NewSpecWithJSONString(testPlatformSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		module   string
+		platform string
+		want     string
+	}{
+		{module: "Foo", platform: SpecPlatformAny, want: "AppKitExt,Common,CoreIOS,CoreMac,Foo/Core,UIKitExt"},
+		{module: "Foo", platform: SpecPlatformIOS, want: "Common,CoreIOS,Foo/Core,UIKitExt"},
+		{module: "Foo", platform: SpecPlatformOSX, want: "AppKitExt,Common,CoreMac,Foo/Core"},
+		{module: "Foo", platform: ":macos", want: "AppKitExt,Common,CoreMac,Foo/Core"},
+		{module: "Foo", platform: SpecPlatformTVOS, want: "Common,Foo/Core"},
+		{module: "Foo/Core", platform: SpecPlatformIOS, want: "Common,CoreIOS,UIKitExt"},
+		{module: "Foo/Core", platform: SpecPlatformWatchOS, want: "Common"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.module+"@"+tt.platform, func(t *testing.T) {
+			if got := testDependNames(aSpec.GetAllDepends(tt.module, tt.platform)); got != tt.want {
+				t.Errorf("GetAllDepends(%q, %q) = %s, want %s", tt.module, tt.platform, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpecSupportsPlatform(t *testing.T) {
+	aSpec, err := NewSpecWithJSONString(testPlatformSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		platform string
+		want     bool
+		version  string
+	}{
+		{platform: SpecPlatformAny, want: true},
+		{platform: SpecPlatformIOS, want: true, version: "9.0"},
+		{platform: "macos", want: true, version: "10.12"},
+		{platform: SpecPlatformTVOS, want: true},
+		{platform: SpecPlatformWatchOS},
+	}
+	for _, tt := range tests {
+		if got := aSpec.SupportsPlatform(tt.platform); got != tt.want {
+			t.Errorf("SupportsPlatform(%q) = %v, want %v", tt.platform, got, tt.want)
+		}
+		if v, _ := aSpec.Platforms.Version(tt.platform); v != tt.version {
+			t.Errorf("Version(%q) = %q, want %q", tt.platform, v, tt.version)
+		}
+	}
+	b, err := aSpec.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := normalizedJSON(t, b), normalizedJSON(t, []byte(testPlatformSpec)); got != want {
+		t.Errorf("JSON() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPodfileFillLocalModuleDependsPlatform(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(path.Join(dir, "Foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path.Join(dir, "Foo", "Foo.podspec.json"), []byte(testPlatformSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := `target 'Phone' do
+  platform :ios, '12.0'
+  pod 'Foo', :path => 'Foo'
+end
+
+target 'Desktop' do
+  platform :osx, '10.15'
+  pod 'Foo/Core', :path => 'Foo'
+end
+
+target 'Tool' do
+  pod 'Foo', :path => 'Foo'
+end
+`
+	aPodfile, err := NewPodfileWithBytes(path.Join(dir, "Podfile"), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	aPodfile.FillLocalModuleDepends(2, nil)
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "Phone", want: "Common,CoreIOS,UIKitExt"},
+		{target: "Desktop", want: "AppKitExt,Common,CoreMac"},
+		{target: "Tool", want: "AppKitExt,Common,CoreIOS,CoreMac,UIKitExt"},
+	}
+	for _, tt := range tests {
+		aModule := aPodfile.TargetWithName(tt.target).Modules[0]
+		names := make([]string, 0, len(aModule.Depends))
+		for _, aDepend := range aModule.Depends {
+			names = append(names, aDepend.N)
+		}
+		sort.Strings(names)
+		if got := strings.Join(names, ","); got != tt.want || aModule.V != "1.0" {
+			t.Errorf("%s: %s %s depends on %s, want %s", tt.target, aModule.N, aModule.V, got, tt.want)
+		}
+	}
+}
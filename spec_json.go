@@ -0,0 +1,238 @@
+package pod
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	fdt "github.com/go-hayden-base/foundation"
+)
+
+var jsonFieldIndexesCache sync.Map
+
+// *** Private Aliases, no methods so encoding/json does not recurse ***
+type p_spec Spec
+type p_spec_platform SpecPlatform
+type p_spec_source SpecSource
+type p_spec_license SpecLicense
+
+// ** Spec JSON Impl **
+func (s *Spec) UnmarshalJSON(b []byte) error {
+	extra, empty, err := unmarshalWithExtra(b, (*p_spec)(s))
+	s.Extra, s.empty = extra, empty
+	return err
+}
+
+func (s *Spec) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra((*p_spec)(s), s.Extra, s.empty)
+}
+
+// ** SpecPlatform JSON Impl **
+func (s *SpecPlatform) UnmarshalJSON(b []byte) error {
+	extra, empty, err := unmarshalWithExtra(b, (*p_spec_platform)(s))
+	if err != nil {
+		return err
+	}
+	s.Extra, s.empty = extra, empty
+	var raw map[string]interface{}
+	json.Unmarshal(b, &raw)
+	for key, value := range raw {
+		if fdt.SliceContainsStr(key, SpecPlatforms) && value == nil {
+			if s.declared == nil {
+				s.declared = make(map[string]bool)
+			}
+			s.declared[key] = true
+		}
+	}
+	return nil
+}
+
+func (s *SpecPlatform) MarshalJSON() ([]byte, error) {
+	if len(s.declared) == 0 {
+		return marshalWithExtra((*p_spec_platform)(s), s.Extra, s.empty)
+	}
+	extra := make(map[string]json.RawMessage, len(s.Extra)+len(s.declared))
+	for key, raw := range s.Extra {
+		extra[key] = raw
+	}
+	for key := range s.declared {
+		if v, _ := s.Version(key); v == "" {
+			extra[key] = json.RawMessage("null")
+		}
+	}
+	return marshalWithExtra((*p_spec_platform)(s), extra, s.empty)
+}
+
+// ** SpecSource JSON Impl **
+func (s *SpecSource) UnmarshalJSON(b []byte) error {
+	extra, empty, err := unmarshalWithExtra(b, (*p_spec_source)(s))
+	s.Extra, s.empty = extra, empty
+	return err
+}
+
+func (s *SpecSource) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra((*p_spec_source)(s), s.Extra, s.empty)
+}
+
+// ** SpecLicense JSON Impl **
+func (s *SpecLicense) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		*s = SpecLicense{Type: str, Single: true}
+		return nil
+	}
+	extra, empty, err := unmarshalWithExtra(b, (*p_spec_license)(s))
+	s.Extra, s.empty = extra, empty
+	s.Single = false
+	return err
+}
+
+func (s *SpecLicense) MarshalJSON() ([]byte, error) {
+	if s.Single && s.File == "" && s.Text == "" && len(s.Extra) == 0 {
+		return json.Marshal(s.Type)
+	}
+	return marshalWithExtra((*p_spec_license)(s), s.Extra, s.empty)
+}
+
+// ** SpecStrings Impl **
+func NewSpecStrings(values ...string) *SpecStrings {
+	return &SpecStrings{Values: values, Single: len(values) == 1}
+}
+
+func (s *SpecStrings) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		*s = SpecStrings{Values: []string{str}, Single: true}
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(b, &values); err != nil {
+		return err
+	}
+	*s = SpecStrings{Values: values}
+	return nil
+}
+
+func (s SpecStrings) MarshalJSON() ([]byte, error) {
+	if s.Single && len(s.Values) == 1 {
+		return json.Marshal(s.Values[0])
+	}
+	if s.Values == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(s.Values)
+}
+
+// ** Func Private **
+
+// unmarshalWithExtra decodes b into the struct v points to and returns the
+// keys v has no field for, then the keys of a field given an empty value
+// (null, "", {} or []) that omitempty would drop. A key whose value does not
+// fit its field is kept as extra too, so a strange spec still loads.
+func unmarshalWithExtra(b []byte, v interface{}) (map[string]json.RawMessage, map[string]json.RawMessage, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, nil, err
+	}
+	known := jsonFieldIndexes(reflect.TypeOf(v).Elem())
+	var extra, empty map[string]json.RawMessage
+	addExtra := func(key string, value json.RawMessage) {
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[key] = value
+	}
+	addEmpty := func(key string, value json.RawMessage) {
+		if empty == nil {
+			empty = make(map[string]json.RawMessage)
+		}
+		empty[key] = value
+	}
+	if err := json.Unmarshal(b, v); err == nil {
+		for key, value := range raw {
+			if _, ok := known[key]; !ok {
+				addExtra(key, value)
+			} else if isEmptyJSON(value) {
+				addEmpty(key, value)
+			}
+		}
+		return extra, empty, nil
+	}
+
+	elem := reflect.ValueOf(v).Elem()
+	elem.Set(reflect.Zero(elem.Type()))
+	for key, value := range raw {
+		if idx, ok := known[key]; ok {
+			one, err := json.Marshal(map[string]json.RawMessage{key: value})
+			if err == nil && json.Unmarshal(one, v) == nil {
+				if isEmptyJSON(value) {
+					addEmpty(key, value)
+				}
+				continue
+			}
+			field := elem.Field(idx)
+			field.Set(reflect.Zero(field.Type()))
+		}
+		addExtra(key, value)
+	}
+	return extra, empty, nil
+}
+
+// marshalWithExtra encodes v and adds the keys of extras it does not set.
+func marshalWithExtra(v interface{}, extras ...map[string]json.RawMessage) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	size := 0
+	for _, extra := range extras {
+		size += len(extra)
+	}
+	if size == 0 {
+		return b, nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	for _, extra := range extras {
+		for key, value := range extra {
+			if _, ok := m[key]; !ok {
+				m[key] = value
+			}
+		}
+	}
+	return json.Marshal(m)
+}
+
+// isEmptyJSON tells if value is null, "", {} or [].
+func isEmptyJSON(value json.RawMessage) bool {
+	value = bytes.TrimSpace(value)
+	switch {
+	case len(value) < 2:
+		return false
+	case string(value) == "null" || string(value) == `""`:
+		return true
+	case value[0] == '{' || value[0] == '[':
+		return len(bytes.TrimSpace(value[1:len(value)-1])) == 0
+	}
+	return false
+}
+
+// jsonFieldIndexes maps the json keys of a struct type to its fields.
+func jsonFieldIndexes(t reflect.Type) map[string]int {
+	if indexes, ok := jsonFieldIndexesCache.Load(t); ok {
+		return indexes.(map[string]int)
+	}
+	indexes := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if tag != "" && tag != "-" {
+			indexes[tag] = i
+		}
+	}
+	jsonFieldIndexesCache.Store(t, indexes)
+	return indexes
+}
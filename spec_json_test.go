@@ -0,0 +1,221 @@
+package pod
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// testAFNetworkingSpec is AFNetworking.podspec.json 4.0.1 from the trunk
+// repo, with a few keys CocoaPods does not know added.
+const testAFNetworkingSpec = `{
+  "name": "AFNetworking",
+  "version": "4.0.1",
+  "license": "MIT",
+  "summary": "A delightful networking framework for Apple platforms.",
+  "homepage": "https://github.com/AFNetworking/AFNetworking",
+  "social_media_url": "https://twitter.com/AFNetworking",
+  "authors": {
+    "Mattt Thompson": "m@mattt.me"
+  },
+  "source": {
+    "git": "https://github.com/AFNetworking/AFNetworking.git",
+    "tag": "4.0.1",
+    "x_mirror": "https://mirror.example.com/AFNetworking.git"
+  },
+  "platforms": {
+    "ios": "9.0",
+    "osx": "10.10",
+    "watchos": "2.0",
+    "tvos": "9.0",
+    "visionos": "1.0"
+  },
+  "ios": {
+    "pod_target_xcconfig": {
+      "PRODUCT_BUNDLE_IDENTIFIER": "com.alamofire.AFNetworking"
+    }
+  },
+  "osx": {
+    "pod_target_xcconfig": {
+      "PRODUCT_BUNDLE_IDENTIFIER": "com.alamofire.AFNetworking"
+    }
+  },
+  "source_files": "AFNetworking/AFNetworking.h",
+  "x_internal": {"owner": "network", "reviewed": true},
+  "subspecs": [
+    {
+      "name": "Serialization",
+      "source_files": "AFNetworking/AFURL{Request,Response}Serialization.{h,m}"
+    },
+    {
+      "name": "Security",
+      "source_files": "AFNetworking/AFSecurityPolicy.{h,m}"
+    },
+    {
+      "name": "Reachability",
+      "platforms": {
+        "ios": "9.0",
+        "osx": "10.10",
+        "tvos": "9.0"
+      },
+      "source_files": "AFNetworking/AFNetworkReachabilityManager.{h,m}"
+    },
+    {
+      "name": "NSURLSession",
+      "dependencies": {
+        "AFNetworking/Serialization": [],
+        "AFNetworking/Security": []
+      },
+      "ios": {
+        "dependencies": {
+          "AFNetworking/Reachability": []
+        }
+      },
+      "osx": {
+        "dependencies": {
+          "AFNetworking/Reachability": []
+        }
+      },
+      "tvos": {
+        "dependencies": {
+          "AFNetworking/Reachability": []
+        }
+      },
+      "source_files": [
+        "AFNetworking/AF{URL,HTTP}SessionManager.{h,m}",
+        "AFNetworking/AFCompatibilityMacros.h"
+      ]
+    },
+    {
+      "name": "UIKit",
+      "platforms": {
+        "ios": "9.0",
+        "tvos": "9.0"
+      },
+      "dependencies": {
+        "AFNetworking/NSURLSession": []
+      },
+      "source_files": "UIKit+AFNetworking"
+    }
+  ]
+}`
+
+// testAFNetworkingSpecWithEmpty gives explicit empty values to
+// testAFNetworkingSpec, as some specs of the trunk repo do.
+var testAFNetworkingSpecWithEmpty = strings.NewReplacer(
+	`"social_media_url": "https://twitter.com/AFNetworking",`,
+	`"social_media_url": "", "documentation_url": "", "deprecated": null,`,
+	`"source_files": "AFNetworking/AFNetworking.h",`,
+	`"source_files": "AFNetworking/AFNetworking.h", "user_target_xcconfig": {}, "resources": [], "testspecs": [],`,
+	`"name": "Security",`,
+	`"name": "Security", "dependencies": {}, "frameworks": [ ], "ios": {},`,
+	`"tag": "4.0.1",`,
+	`"tag": "4.0.1", "branch": "",`,
+).Replace(testAFNetworkingSpec)
+
+// normalizedJSON decodes b and encodes it again, so that key order and
+// spacing do not matter when comparing.
+func normalizedJSON(t *testing.T, b []byte) string {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatalf("%v: %s", err, b)
+	}
+	res, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(res)
+}
+
+func TestSpecJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{name: "trunk podspec", json: testAFNetworkingSpec},
+		{name: "trunk podspec with empty values", json: testAFNetworkingSpecWithEmpty},
+		{name: "license hash", json: `{"name": "Foo", "license": {"type": "MIT", "file": "LICENSE", "x": 1}}`},
+		{name: "single and list strings", json: `{"name": "Foo", "frameworks": "UIKit", "libraries": ["z", "c++"]}`},
+		{name: "mistyped known key", json: `{"name": "Foo", "version": 1.0, "source_files": {"a": "b"}}`},
+		{name: "flags", json: `{"name": "Foo", "static_framework": false, "requires_arc": ["Foo/*.m"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aSpec, err := NewSpecWithJSONString(tt.json)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := aSpec.JSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := normalizedJSON(t, b), normalizedJSON(t, []byte(tt.json)); got != want {
+				t.Errorf("JSON() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestSpecJSONExtra(t *testing.T) {
+	aSpec, err := NewSpecWithJSONString(testAFNetworkingSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aSpec.Version != "4.0.1" || !aSpec.License.Single || aSpec.License.Type != "MIT" {
+		t.Errorf("spec = %s %+v", aSpec.Version, aSpec.License)
+	}
+	if got := string(aSpec.Extra["x_internal"]); got != `{"owner": "network", "reviewed": true}` {
+		t.Errorf("Extra[x_internal] = %s", got)
+	}
+	if aSpec.Source.Tag != "4.0.1" || len(aSpec.Source.Extra) != 1 || aSpec.Platforms.IOS != "9.0" || aSpec.Platforms.Extra["visionos"] == nil {
+		t.Errorf("source extra = %v, platform extra = %v", aSpec.Source.Extra, aSpec.Platforms.Extra)
+	}
+	aSubspec := aSpec.Subspecs[3]
+	if aSubspec.SourceFiles == nil || aSubspec.SourceFiles.Single || len(aSubspec.SourceFiles.Values) != 2 {
+		t.Errorf("%s source_files = %+v", aSubspec.Name, aSubspec.SourceFiles)
+	}
+
+	aSpec, err = NewSpecWithJSONString(`{"name": "Foo", "version": 1.0}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aSpec.Name != "Foo" || aSpec.Version != "" || string(aSpec.Extra["version"]) != "1.0" {
+		t.Errorf("mistyped version = %q, extra = %v", aSpec.Version, aSpec.Extra)
+	}
+}
+
+func TestSpecJSONEmptyValues(t *testing.T) {
+	aSpec, err := NewSpecWithJSONString(testAFNetworkingSpecWithEmpty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(aSpec.Extra) != 1 || len(aSpec.Subspecs[1].Extra) != 0 || len(aSpec.Source.Extra) != 1 {
+		t.Errorf("extra = %v, %v, %v, want the unknown keys only", aSpec.Extra, aSpec.Subspecs[1].Extra, aSpec.Source.Extra)
+	}
+	// A value set after reading replaces the empty one
+	aSpec.DocumentationURL = "https://example.com/docs"
+	aSpec.Subspecs[1].Dependences = SpecDenpendence{"AFNetworking/Serialization": nil}
+	b, err := aSpec.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		DocumentationURL string `json:"documentation_url"`
+		Subspecs         []struct {
+			Dependencies map[string][]string `json:"dependencies"`
+		} `json:"subspecs"`
+	}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.DocumentationURL != aSpec.DocumentationURL {
+		t.Errorf("documentation_url = %q, want %q", got.DocumentationURL, aSpec.DocumentationURL)
+	}
+	if deps := got.Subspecs[1].Dependencies; len(deps) != 1 {
+		t.Errorf("Security dependencies = %v, want AFNetworking/Serialization", deps)
+	}
+}
@@ -0,0 +1,307 @@
+package pod
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	fdt "github.com/go-hayden-base/foundation"
+	ver "github.com/go-hayden-base/version"
+)
+
+var regJSONPathName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+var regTagVersion = regexp.MustCompile(`#\{\s*[a-z_]+\.version(?:\.to_s)?\s*\}`)
+
+// ** SpecDiagnostic Impl **
+func (s *SpecDiagnostic) String() string {
+	return s.Severity + " " + s.Path + ": " + s.Message
+}
+
+// ** SpecDiagnostics Impl **
+func (s SpecDiagnostics) HasErrors() bool {
+	for _, d := range s {
+		if d.Severity == SpecLintError {
+			return true
+		}
+	}
+	return false
+}
+
+// ** Spec Linter **
+
+// specLinter walks a hashed spec, edges are the dependencies between the
+// subspecs of the root, keyed by ModulePath.
+type specLinter struct {
+	root        *Spec
+	diagnostics SpecDiagnostics
+	specs       map[string]*Spec
+	jsonPaths   map[string]string
+	parents     map[string]string
+	edges       map[string][]string
+}
+
+func (s *specLinter) report(severity, code, path, message string) {
+	s.diagnostics = append(s.diagnostics, &SpecDiagnostic{Severity: severity, Code: code, Path: path, Message: message})
+}
+
+func (s *specLinter) lintRoot() {
+	if s.root.Name == "" {
+		s.report(SpecLintError, SpecLintMissingName, "$.name", "缺少 name")
+	}
+	version := s.root.Version
+	if version == "" {
+		s.report(SpecLintError, SpecLintMissingVersion, "$.version", "缺少 version")
+		return
+	}
+	if !ver.IsVersion(version) {
+		s.report(SpecLintError, SpecLintInvalidVersion, "$.version", "version 不是合法的版本号: "+version)
+		return
+	}
+	if s.root.Source != nil && s.root.Source.Tag != "" && !tagMatchesVersion(s.root.Source.Tag, version) {
+		s.report(SpecLintWarning, SpecLintTagMismatch, "$.source.tag", "source.tag "+s.root.Source.Tag+" 与 version "+version+" 不一致")
+	}
+}
+
+// lintSpec checks aSpec at jsonPath and its children, extension is true for
+// test and app specs, which may depend on the root.
+func (s *specLinter) lintSpec(aSpec *Spec, modulePath, jsonPath string, extension bool) {
+	if aSpec != s.root && aSpec.Name == "" {
+		s.report(SpecLintError, SpecLintMissingName, jsonPath+".name", "缺少 name")
+	}
+	if !extension {
+		s.specs[modulePath] = aSpec
+		s.jsonPaths[modulePath] = jsonPath
+	}
+	s.lintPlatforms(aSpec, jsonPath)
+	s.lintDefaultSubspecs(aSpec, jsonPath)
+	s.lintDepends(aSpec.Dependences, modulePath, jsonPath+".dependencies", extension)
+	for _, platform := range SpecPlatforms {
+		if aPlatformSpec := aSpec.PlatformSpec(platform); aPlatformSpec != nil {
+			s.lintDepends(aPlatformSpec.Dependences, modulePath, jsonPath+"."+platform+".dependencies", extension)
+		}
+	}
+	for idx, subspec := range aSpec.Subspecs {
+		subPath := subspec.ModulePath
+		if extension || subPath == "" {
+			subPath = modulePath + "/" + subspec.Name
+		}
+		if !extension {
+			s.parents[subPath] = modulePath
+		}
+		s.lintSpec(subspec, subPath, jsonPath+".subspecs["+strconv.Itoa(idx)+"]", extension)
+	}
+	for idx, testSpec := range aSpec.Testspecs {
+		s.lintSpec(testSpec, modulePath+"/"+testSpec.Name, jsonPath+".testspecs["+strconv.Itoa(idx)+"]", true)
+	}
+	for idx, appSpec := range aSpec.Appspecs {
+		s.lintSpec(appSpec, modulePath+"/"+appSpec.Name, jsonPath+".appspecs["+strconv.Itoa(idx)+"]", true)
+	}
+}
+
+func (s *specLinter) lintPlatforms(aSpec *Spec, jsonPath string) {
+	if aSpec.Platforms == nil {
+		return
+	}
+	for _, platform := range SpecPlatforms {
+		if v, _ := aSpec.Platforms.Version(platform); v != "" && !ver.IsVersion(v) {
+			s.report(SpecLintError, SpecLintInvalidPlatform, jsonPath+".platforms."+platform, platform+" 的版本无法解析: "+v)
+		}
+	}
+	keys := make([]string, 0, len(aSpec.Platforms.Extra))
+	for key := range aSpec.Platforms.Extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if fdt.SliceContainsStr(key, SpecPlatforms) {
+			s.report(SpecLintError, SpecLintInvalidPlatform, jsonPathKey(jsonPath+".platforms", key), key+" 的版本无法解析: "+string(aSpec.Platforms.Extra[key]))
+		} else {
+			s.report(SpecLintWarning, SpecLintInvalidPlatform, jsonPathKey(jsonPath+".platforms", key), "未知的平台 "+key)
+		}
+	}
+}
+
+func (s *specLinter) lintDefaultSubspecs(aSpec *Spec, jsonPath string) {
+	check := func(v interface{}, path string) {
+		name, ok := v.(string)
+		if ok && name == "none" {
+			return
+		}
+		for _, subspec := range aSpec.Subspecs {
+			if ok && subspec.Name == name {
+				return
+			}
+		}
+		s.report(SpecLintError, SpecLintUnknownDefaultSubspec, path, "default_subspecs 中的 "+name+" 不是子spec")
+	}
+	switch t := aSpec.DefaultSpecs.(type) {
+	case string:
+		check(t, jsonPath+".default_subspecs")
+	case []interface{}:
+		for idx, item := range t {
+			check(item, jsonPath+".default_subspecs["+strconv.Itoa(idx)+"]")
+		}
+	}
+}
+
+func (s *specLinter) lintDepends(depends SpecDenpendence, modulePath, jsonPath string, extension bool) {
+	for _, dep := range sortedKeys(depends) {
+		if s.root.Name == "" || fdt.StrSplitFirst(dep, "/") != s.root.Name {
+			continue
+		}
+		path := jsonPathKey(jsonPath, dep)
+		if dep == modulePath || strings.HasPrefix(modulePath, dep+"/") {
+			if !extension || dep == modulePath {
+				s.report(SpecLintError, SpecLintSelfDependency, path, modulePath+" 不能依赖自身或父spec "+dep)
+			}
+			continue
+		}
+		if len(s.root.getPathSubspecs(dep)) == 0 {
+			s.report(SpecLintError, SpecLintUnknownSubspec, path, "依赖的子spec "+dep+" 不存在")
+			continue
+		}
+		if !extension {
+			s.edges[modulePath] = append(s.edges[modulePath], dep)
+		}
+	}
+}
+
+// lintCycles finds the cycles between subspecs. A subspec depends on what
+// its parents depend on, and depending on a spec pulls its default subspecs.
+func (s *specLinter) lintCycles() {
+	names := make([]string, 0, len(s.specs))
+	for name := range s.specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[string]int, len(names))
+	reported := make(map[string]bool)
+	stack := make([]string, 0, len(names))
+	var visit func(name string)
+	visit = func(name string) {
+		states[name] = visiting
+		stack = append(stack, name)
+		for _, next := range s.dependsOf(name) {
+			switch states[next] {
+			case unvisited:
+				visit(next)
+			case visiting:
+				var cycle []string
+				for idx := len(stack) - 1; idx >= 0; idx-- {
+					if stack[idx] == next {
+						cycle = append([]string{}, stack[idx:]...)
+						break
+					}
+				}
+				s.reportCycle(cycle, reported)
+			}
+		}
+		stack = stack[:len(stack)-1]
+		states[name] = visited
+	}
+	for _, name := range names {
+		if states[name] == unvisited {
+			visit(name)
+		}
+	}
+}
+
+func (s *specLinter) dependsOf(name string) []string {
+	var res []string
+	seen := map[string]bool{name: true}
+	add := func(dep string) {
+		if !seen[dep] {
+			seen[dep] = true
+			res = append(res, dep)
+		}
+	}
+	for p := name; p != ""; p = s.parents[p] {
+		for _, dep := range s.edges[p] {
+			add(dep)
+		}
+	}
+	if aSpec := s.specs[name]; aSpec != nil {
+		children := aSpec.DefaultSpecsMap
+		if children == nil {
+			children = aSpec.SingleSpecsMap
+		}
+		for _, child := range children {
+			if _, ok := s.specs[child.ModulePath]; ok {
+				add(child.ModulePath)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// reportCycle reports cycle once, started from its smallest module.
+func (s *specLinter) reportCycle(cycle []string, reported map[string]bool) {
+	if len(cycle) == 0 {
+		return
+	}
+	start := 0
+	for idx, name := range cycle {
+		if name < cycle[start] {
+			start = idx
+		}
+	}
+	cycle = append(cycle[start:], cycle[:start]...)
+	key := strings.Join(cycle, "\x00")
+	if reported[key] {
+		return
+	}
+	reported[key] = true
+	s.report(SpecLintError, SpecLintSubspecCycle, s.jsonPaths[cycle[0]], "子spec循环依赖: "+strings.Join(append(cycle, cycle[0]), " -> "))
+}
+
+// ** Func Public **
+
+// LintSpec checks aSpec offline before it is pushed to a repo: name and
+// version, source.tag, default_subspecs, the dependencies between its
+// subspecs and the platform versions. aSpec is hashed by the check.
+func LintSpec(aSpec *Spec) SpecDiagnostics {
+	if aSpec == nil {
+		return nil
+	}
+	aSpec.HashSpec()
+	linter := &specLinter{
+		root:      aSpec,
+		specs:     make(map[string]*Spec),
+		jsonPaths: make(map[string]string),
+		parents:   make(map[string]string),
+		edges:     make(map[string][]string),
+	}
+	linter.lintRoot()
+	modulePath := aSpec.ModulePath
+	if modulePath == "" {
+		modulePath = aSpec.Name
+	}
+	linter.lintSpec(aSpec, modulePath, "$", false)
+	linter.lintCycles()
+	return linter.diagnostics
+}
+
+// ** Func Private **
+
+// tagMatchesVersion accepts a tag equal to version or to "v" + version, once
+// `#{s.version}` is replaced.
+func tagMatchesVersion(tag, version string) bool {
+	tag = regTagVersion.ReplaceAllLiteralString(tag, version)
+	return tag == version || tag == "v"+version
+}
+
+// jsonPathKey appends key to a JSON path, in brackets when it is not a
+// plain name such as "Foo/Core".
+func jsonPathKey(path, key string) string {
+	if regJSONPathName.MatchString(key) {
+		return path + "." + key
+	}
+	return path + "[" + strconv.Quote(key) + "]"
+}
@@ -0,0 +1,124 @@
+package pod
+
+import (
+	"strings"
+	"testing"
+)
+
+func lintCodes(t *testing.T, specJSON string) string {
+	t.Helper()
+	aSpec, err := NewSpecWithJSONBytes([]byte(specJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes := make([]string, 0, 2)
+	for _, d := range LintSpec(aSpec) {
+		codes = append(codes, d.Code+" "+d.Path)
+	}
+	return strings.Join(codes, "; ")
+}
+
+func TestLintSpecTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{tag: "1.2.0", want: ""},
+		{tag: "v1.2.0", want: ""},
+		{tag: "#{s.version}", want: ""},
+		{tag: "v#{spec.version.to_s}", want: ""},
+		{tag: "release-1.2.0", want: SpecLintTagMismatch + " $.source.tag"},
+		{tag: "1.2.0.1", want: SpecLintTagMismatch + " $.source.tag"},
+		{tag: "11.2.0", want: SpecLintTagMismatch + " $.source.tag"},
+	}
+	for _, tt := range tests {
+		spec := `{"name": "Foo", "version": "1.2.0", "source": {"git": "https://example.com/Foo.git", "tag": "` + tt.tag + `"}}`
+		if got := lintCodes(t, spec); got != tt.want {
+			t.Errorf("tag %s: diagnostics = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestLintSpec(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want string
+	}{
+		{
+			name: "valid",
+			spec: `{"name": "Foo", "version": "1.0", "default_subspecs": "Core",
+				"subspecs": [{"name": "Core"}, {"name": "Util", "dependencies": {"Foo/Core": []}}]}`,
+			want: "",
+		},
+		{
+			name: "missing name and bad version",
+			spec: `{"version": "one"}`,
+			want: SpecLintMissingName + " $.name; " + SpecLintInvalidVersion + " $.version",
+		},
+		{
+			name: "missing version",
+			spec: `{"name": "Foo"}`,
+			want: SpecLintMissingVersion + " $.version",
+		},
+		{
+			name: "tag mismatch",
+			spec: `{"name": "Foo", "version": "1.0", "source": {"git": "https://example.com/Foo.git", "tag": "2.0"}}`,
+			want: SpecLintTagMismatch + " $.source.tag",
+		},
+		{
+			name: "invalid platforms",
+			spec: `{"name": "Foo", "version": "1.0", "platforms": {"ios": "nine", "osx": 10, "visionos": "1.0"}}`,
+			want: SpecLintInvalidPlatform + " $.platforms.ios; " + SpecLintInvalidPlatform + " $.platforms.osx; " + SpecLintInvalidPlatform + " $.platforms.visionos",
+		},
+		{
+			name: "platform dependency on an unknown subspec",
+			spec: `{"name": "Foo", "version": "1.0", "subspecs": [{"name": "Core"}], "ios": {"dependencies": {"Foo/Nope": []}}}`,
+			want: SpecLintUnknownSubspec + ` $.ios.dependencies["Foo/Nope"]`,
+		},
+		{
+			name: "unknown default subspec",
+			spec: `{"name": "Foo", "version": "1.0", "default_subspecs": ["Core", "Nope"], "subspecs": [{"name": "Core"}]}`,
+			want: SpecLintUnknownDefaultSubspec + " $.default_subspecs[1]",
+		},
+		{
+			name: "self dependency and unknown subspec",
+			spec: `{"name": "Foo", "version": "1.0", "subspecs": [{"name": "Core", "dependencies": {"Foo": [], "Foo/Nope": []}}]}`,
+			want: SpecLintSelfDependency + ` $.subspecs[0].dependencies.Foo; ` + SpecLintUnknownSubspec + ` $.subspecs[0].dependencies["Foo/Nope"]`,
+		},
+		{
+			name: "cycle",
+			spec: `{"name": "Foo", "version": "1.0", "subspecs": [
+				{"name": "A", "dependencies": {"Foo/B": []}},
+				{"name": "B", "dependencies": {"Foo/A": []}}]}`,
+			want: SpecLintSubspecCycle + " $.subspecs[0]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lintCodes(t, tt.spec); got != tt.want {
+				t.Errorf("diagnostics = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpecDiagnosticsHasErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{spec: `{"name": "Foo", "version": "1.0"}`, want: false},
+		{spec: `{"name": "Foo", "version": "1.0", "source": {"tag": "2.0"}}`, want: false},
+		{spec: `{"name": "Foo", "version": "one"}`, want: true},
+	}
+	for _, tt := range tests {
+		aSpec, err := NewSpecWithJSONString(tt.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := LintSpec(aSpec).HasErrors(); got != tt.want {
+			t.Errorf("%s: HasErrors() = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
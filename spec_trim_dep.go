@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"strings"
+
+	fdt "github.com/go-hayden-base/foundation"
 )
 
 func SpecTrimDependency(spec []byte) ([]byte, error) {
@@ -50,6 +52,17 @@ func funcDoTrimDependency(spec map[string]interface{}, rootPath string) error {
 			}
 			continue
 		}
+		if fdt.SliceContainsStr(key, SpecPlatforms) {
+			if aPlatformObj, ok := val.(map[string]interface{}); ok {
+				if err := funcDoTrimDependency(aPlatformObj, rootPath); err != nil {
+					return err
+				}
+				if len(aPlatformObj) == 0 {
+					delete(spec, key)
+				}
+			}
+			continue
+		}
 		if key != "subspecs" {
 			continue
 		}
@@ -1,30 +1,186 @@
 package pod
 
+import "encoding/json"
+
+// Platforms of a spec, SpecPlatformAny takes all of them
+const (
+	SpecPlatformAny     = ""
+	SpecPlatformIOS     = "ios"
+	SpecPlatformOSX     = "osx"
+	SpecPlatformTVOS    = "tvos"
+	SpecPlatformWatchOS = "watchos"
+)
+
+var SpecPlatforms = []string{SpecPlatformIOS, SpecPlatformOSX, SpecPlatformTVOS, SpecPlatformWatchOS}
+
+// Spec follows the podspec JSON schema. Attributes taking several shapes in
+// the DSL are SpecStrings, SpecLicense or interface{}, keys with no field
+// here are kept in Extra and keys given an empty value are remembered, so
+// JSON gives back the document that was read.
 type Spec struct {
-	FilePath        string           `json:"-" bson:"-"`
-	DefaultSpecsMap map[string]*Spec `json:"-" bson:"-"`
-	SingleSpecsMap  map[string]*Spec `json:"-" bson:"-"`
-	ModulePath      string           `json:"-" bson:"-"`
-	hasHash         bool             `json:"-" bson:"-"`
-
-	Name         string          `json:"name,omitempty" bson:"name,omitempty"`
-	Version      string          `json:"version,omitempty" bson:"version,omitempty"`
-	Platforms    *SpecPlatform   `json:"platforms,omitempty" bson:"platforms,omitempty"`
-	Source       *SpecSource     `json:"source,omitempty" bson:"source,omitempty"`
-	DefaultSpecs interface{}     `json:"default_subspecs,omitempty" bson:"default_subspecs,omitempty"`
-	Dependences  SpecDenpendence `json:"dependencies,omitempty" bson:"dependencies,omitempty"`
-	Subspecs     []*Spec         `json:"subspecs,omitempty" bson:"subspecs,omitempty"`
+	FilePath        string                     `json:"-" bson:"-"`
+	DefaultSpecsMap map[string]*Spec           `json:"-" bson:"-"`
+	SingleSpecsMap  map[string]*Spec           `json:"-" bson:"-"`
+	ModulePath      string                     `json:"-" bson:"-"`
+	Extra           map[string]json.RawMessage `json:"-" bson:"-"`
+	hasHash         bool                       `json:"-" bson:"-"`
+	empty           map[string]json.RawMessage `json:"-" bson:"-"`
+
+	// Root
+	Name                string       `json:"name,omitempty" bson:"name,omitempty"`
+	Version             string       `json:"version,omitempty" bson:"version,omitempty"`
+	CocoapodsVersion    string       `json:"cocoapods_version,omitempty" bson:"cocoapods_version,omitempty"`
+	Authors             interface{}  `json:"authors,omitempty" bson:"authors,omitempty"`
+	SocialMediaURL      string       `json:"social_media_url,omitempty" bson:"social_media_url,omitempty"`
+	License             *SpecLicense `json:"license,omitempty" bson:"license,omitempty"`
+	Homepage            string       `json:"homepage,omitempty" bson:"homepage,omitempty"`
+	Readme              string       `json:"readme,omitempty" bson:"readme,omitempty"`
+	Changelog           string       `json:"changelog,omitempty" bson:"changelog,omitempty"`
+	Source              *SpecSource  `json:"source,omitempty" bson:"source,omitempty"`
+	Summary             string       `json:"summary,omitempty" bson:"summary,omitempty"`
+	Description         string       `json:"description,omitempty" bson:"description,omitempty"`
+	Screenshots         *SpecStrings `json:"screenshots,omitempty" bson:"screenshots,omitempty"`
+	DocumentationURL    string       `json:"documentation_url,omitempty" bson:"documentation_url,omitempty"`
+	PrepareCommand      string       `json:"prepare_command,omitempty" bson:"prepare_command,omitempty"`
+	StaticFramework     *bool        `json:"static_framework,omitempty" bson:"static_framework,omitempty"`
+	Deprecated          *bool        `json:"deprecated,omitempty" bson:"deprecated,omitempty"`
+	DeprecatedInFavorOf string       `json:"deprecated_in_favor_of,omitempty" bson:"deprecated_in_favor_of,omitempty"`
+	SwiftVersion        string       `json:"swift_version,omitempty" bson:"swift_version,omitempty"`
+	SwiftVersions       *SpecStrings `json:"swift_versions,omitempty" bson:"swift_versions,omitempty"`
+
+	// Platform, IOS to WatchOS hold the attributes scoped to a platform
+	Platforms *SpecPlatform `json:"platforms,omitempty" bson:"platforms,omitempty"`
+	IOS       *Spec         `json:"ios,omitempty" bson:"ios,omitempty"`
+	OSX       *Spec         `json:"osx,omitempty" bson:"osx,omitempty"`
+	TVOS      *Spec         `json:"tvos,omitempty" bson:"tvos,omitempty"`
+	WatchOS   *Spec         `json:"watchos,omitempty" bson:"watchos,omitempty"`
+
+	// Build settings
+	Dependences          SpecDenpendence        `json:"dependencies,omitempty" bson:"dependencies,omitempty"`
+	RequiresArc          interface{}            `json:"requires_arc,omitempty" bson:"requires_arc,omitempty"`
+	Frameworks           *SpecStrings           `json:"frameworks,omitempty" bson:"frameworks,omitempty"`
+	WeakFrameworks       *SpecStrings           `json:"weak_frameworks,omitempty" bson:"weak_frameworks,omitempty"`
+	Libraries            *SpecStrings           `json:"libraries,omitempty" bson:"libraries,omitempty"`
+	CompilerFlags        *SpecStrings           `json:"compiler_flags,omitempty" bson:"compiler_flags,omitempty"`
+	PodTargetXcconfig    map[string]interface{} `json:"pod_target_xcconfig,omitempty" bson:"pod_target_xcconfig,omitempty"`
+	UserTargetXcconfig   map[string]interface{} `json:"user_target_xcconfig,omitempty" bson:"user_target_xcconfig,omitempty"`
+	PrefixHeaderContents *SpecStrings           `json:"prefix_header_contents,omitempty" bson:"prefix_header_contents,omitempty"`
+	PrefixHeaderFile     interface{}            `json:"prefix_header_file,omitempty" bson:"prefix_header_file,omitempty"`
+	ModuleName           string                 `json:"module_name,omitempty" bson:"module_name,omitempty"`
+	HeaderDir            string                 `json:"header_dir,omitempty" bson:"header_dir,omitempty"`
+	HeaderMappingsDir    string                 `json:"header_mappings_dir,omitempty" bson:"header_mappings_dir,omitempty"`
+	ScriptPhases         interface{}            `json:"script_phases,omitempty" bson:"script_phases,omitempty"`
+	InfoPlist            map[string]interface{} `json:"info_plist,omitempty" bson:"info_plist,omitempty"`
+
+	// File patterns
+	SourceFiles        *SpecStrings           `json:"source_files,omitempty" bson:"source_files,omitempty"`
+	PublicHeaderFiles  *SpecStrings           `json:"public_header_files,omitempty" bson:"public_header_files,omitempty"`
+	ProjectHeaderFiles *SpecStrings           `json:"project_header_files,omitempty" bson:"project_header_files,omitempty"`
+	PrivateHeaderFiles *SpecStrings           `json:"private_header_files,omitempty" bson:"private_header_files,omitempty"`
+	VendoredFrameworks *SpecStrings           `json:"vendored_frameworks,omitempty" bson:"vendored_frameworks,omitempty"`
+	VendoredLibraries  *SpecStrings           `json:"vendored_libraries,omitempty" bson:"vendored_libraries,omitempty"`
+	OnDemandResources  interface{}            `json:"on_demand_resources,omitempty" bson:"on_demand_resources,omitempty"`
+	ResourceBundles    map[string]SpecStrings `json:"resource_bundles,omitempty" bson:"resource_bundles,omitempty"`
+	Resources          *SpecStrings           `json:"resources,omitempty" bson:"resources,omitempty"`
+	ExcludeFiles       *SpecStrings           `json:"exclude_files,omitempty" bson:"exclude_files,omitempty"`
+	PreservePaths      *SpecStrings           `json:"preserve_paths,omitempty" bson:"preserve_paths,omitempty"`
+	ModuleMap          interface{}            `json:"module_map,omitempty" bson:"module_map,omitempty"`
+
+	// Subspecs
+	DefaultSpecs    interface{} `json:"default_subspecs,omitempty" bson:"default_subspecs,omitempty"`
+	Subspecs        []*Spec     `json:"subspecs,omitempty" bson:"subspecs,omitempty"`
+	Testspecs       []*Spec     `json:"testspecs,omitempty" bson:"testspecs,omitempty"`
+	Appspecs        []*Spec     `json:"appspecs,omitempty" bson:"appspecs,omitempty"`
+	TestType        string      `json:"test_type,omitempty" bson:"test_type,omitempty"`
+	RequiresAppHost *bool       `json:"requires_app_host,omitempty" bson:"requires_app_host,omitempty"`
+	AppHostName     string      `json:"app_host_name,omitempty" bson:"app_host_name,omitempty"`
+	Scheme          interface{} `json:"scheme,omitempty" bson:"scheme,omitempty"`
 }
 
+// SpecPlatform holds the deployment target of each platform of a spec, a
+// platform given as null (any version) is remembered in declared.
 type SpecPlatform struct {
-	IOS string `json:"ios,omitempty" bson:"ios,omitempty"`
+	IOS     string                     `json:"ios,omitempty" bson:"ios,omitempty"`
+	OSX     string                     `json:"osx,omitempty" bson:"osx,omitempty"`
+	TVOS    string                     `json:"tvos,omitempty" bson:"tvos,omitempty"`
+	WatchOS string                     `json:"watchos,omitempty" bson:"watchos,omitempty"`
+	Extra   map[string]json.RawMessage `json:"-" bson:"-"`
+
+	declared map[string]bool
+	empty    map[string]json.RawMessage
 }
 
 type SpecSource struct {
-	Git    string `json:"git,omitempty" bson:"git,omitempty"`
-	Tag    string `json:"tag,omitempty" bson:"tag,omitempty"`
-	Branch string `json:"branch,omitempty" bson:"branch,omitempty"`
-	Commit string `json:"commit,omitempty" bson:"commit,omitempty"`
+	Git        string                     `json:"git,omitempty" bson:"git,omitempty"`
+	Tag        string                     `json:"tag,omitempty" bson:"tag,omitempty"`
+	Branch     string                     `json:"branch,omitempty" bson:"branch,omitempty"`
+	Commit     string                     `json:"commit,omitempty" bson:"commit,omitempty"`
+	Submodules *bool                      `json:"submodules,omitempty" bson:"submodules,omitempty"`
+	HTTP       string                     `json:"http,omitempty" bson:"http,omitempty"`
+	Type       string                     `json:"type,omitempty" bson:"type,omitempty"`
+	Flatten    *bool                      `json:"flatten,omitempty" bson:"flatten,omitempty"`
+	SHA1       string                     `json:"sha1,omitempty" bson:"sha1,omitempty"`
+	SHA256     string                     `json:"sha256,omitempty" bson:"sha256,omitempty"`
+	Extra      map[string]json.RawMessage `json:"-" bson:"-"`
+
+	empty map[string]json.RawMessage
+}
+
+// SpecLicense is the license of a spec, given as a type alone or as a hash.
+// Single keeps the string form when marshalled.
+type SpecLicense struct {
+	Type   string                     `json:"type,omitempty" bson:"type,omitempty"`
+	File   string                     `json:"file,omitempty" bson:"file,omitempty"`
+	Text   string                     `json:"text,omitempty" bson:"text,omitempty"`
+	Single bool                       `json:"-" bson:"-"`
+	Extra  map[string]json.RawMessage `json:"-" bson:"-"`
+
+	empty map[string]json.RawMessage
+}
+
+// SpecStrings is an attribute given as a string or as a list of strings.
+// Single keeps the string form when marshalled.
+type SpecStrings struct {
+	Values []string `bson:"values,omitempty"`
+	Single bool     `bson:"single,omitempty"`
 }
 
 type SpecDenpendence map[string][]string
+
+// PodspecUnsupportedError is returned by ParsePodspec when the podspec
+// contains Ruby that the native parser can not evaluate.
+type PodspecUnsupportedError struct {
+	FilePath string
+	Line     int
+	Reason   string
+}
+
+// Severities of a SpecDiagnostic
+const (
+	SpecLintError   = "error"
+	SpecLintWarning = "warning"
+)
+
+// Codes of a SpecDiagnostic
+const (
+	SpecLintMissingName           = "missing-name"
+	SpecLintMissingVersion        = "missing-version"
+	SpecLintInvalidVersion        = "invalid-version"
+	SpecLintTagMismatch           = "tag-mismatch"
+	SpecLintUnknownDefaultSubspec = "unknown-default-subspec"
+	SpecLintUnknownSubspec        = "unknown-subspec"
+	SpecLintSelfDependency        = "self-dependency"
+	SpecLintSubspecCycle          = "subspec-cycle"
+	SpecLintInvalidPlatform       = "invalid-platform"
+)
+
+// SpecDiagnostic is a problem LintSpec found, Path is the JSON path of the
+// attribute in the podspec JSON, such as `$.subspecs[1].dependencies["Foo/Core"]`.
+type SpecDiagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+type SpecDiagnostics []*SpecDiagnostic
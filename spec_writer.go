@@ -0,0 +1,289 @@
+package pod
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	fdt "github.com/go-hayden-base/foundation"
+)
+
+var regRubySymbol = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Attributes written first, in the order of `pod spec create`, the others
+// follow sorted by name.
+var podspecAttributeOrder = []string{
+	"name", "version", "summary", "description", "homepage", "license", "authors",
+	"social_media_url", "documentation_url", "screenshots", "source", "platforms",
+}
+
+// Attributes whose hash keys are written as symbols, like `:git => url`
+var podspecSymbolKeyAttributes = []string{"source", "license", "script_phases", "scheme"}
+
+// Keys of child specs and their DSL call
+var podspecChildKeys = [][2]string{
+	{"subspecs", "subspec"}, {"testspecs", "test_spec"}, {"appspecs", "app_spec"},
+}
+
+// ** Spec Podspec Writer **
+
+// PodspecBytes renders the spec as Ruby podspec DSL. Keys of Extra have no
+// DSL attribute and are written through attributes_hash, so `pod ipc spec`
+// on the result gives back the JSON of the spec.
+func (s *Spec) PodspecBytes() ([]byte, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var attributes map[string]interface{}
+	if err := decoder.Decode(&attributes); err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString("Pod::Spec.new do |s|\n")
+	writePodspecAttributes(&buffer, attributes, "s", podfileIndent, true)
+	buffer.WriteString("end\n")
+	return buffer.Bytes(), nil
+}
+
+// SavePodspec writes the podspec to filePath, or to FilePath when filePath
+// is empty and FilePath is a .podspec.
+func (s *Spec) SavePodspec(filePath string) error {
+	if filePath == "" && strings.ToLower(path.Ext(s.FilePath)) == ".podspec" {
+		filePath = s.FilePath
+	}
+	if filePath == "" {
+		return errors.New("请指定podspec文件路径！")
+	}
+	b, err := s.PodspecBytes()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filePath, b, 0644)
+}
+
+// ** Func Private **
+
+// writePodspecAttributes writes the attributes of the spec held in variable
+// v: plain attributes, platform blocks, dependencies and then child specs.
+func writePodspecAttributes(buffer *bytes.Buffer, attributes map[string]interface{}, v, indent string, root bool) {
+	known := jsonFieldIndexes(reflect.TypeOf(p_spec{}))
+	for _, key := range podspecAttributeKeys(attributes) {
+		value := attributes[key]
+		switch {
+		case key == "name" && !root:
+		case key == "platforms":
+			writePodspecPlatforms(buffer, value, v, indent)
+		case key == "dependencies" || fdt.SliceContainsStr(key, SpecPlatforms) || podspecChildCall(key) != "":
+		case key == "test_type":
+			if t, ok := value.(string); ok && regRubySymbol.MatchString(t) {
+				buffer.WriteString(indent + v + ".test_type = :" + t + "\n")
+			} else {
+				buffer.WriteString(indent + v + ".test_type = " + podspecRubyValue(value) + "\n")
+			}
+		default:
+			if _, ok := known[key]; !ok {
+				buffer.WriteString(indent + v + ".attributes_hash[" + rubyString(key) + "] = " + podspecRubyValue(value) + "\n")
+				continue
+			}
+			buffer.WriteString(indent + v + "." + key + " = " + podspecRubyAssignValue(key, value, indent) + "\n")
+		}
+	}
+
+	for _, platform := range SpecPlatforms {
+		scoped, ok := attributes[platform].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range podspecAttributeKeys(scoped) {
+			if key == "dependencies" {
+				continue
+			}
+			if _, ok := known[key]; !ok {
+				buffer.WriteString(indent + "(" + v + ".attributes_hash[" + rubyString(platform) + "] ||= {})[" + rubyString(key) + "] = " + podspecRubyValue(scoped[key]) + "\n")
+				continue
+			}
+			buffer.WriteString(indent + v + "." + platform + "." + key + " = " + podspecRubyAssignValue(key, scoped[key], indent) + "\n")
+		}
+	}
+
+	writePodspecDepends(buffer, attributes["dependencies"], v, indent)
+	for _, platform := range SpecPlatforms {
+		if scoped, ok := attributes[platform].(map[string]interface{}); ok {
+			writePodspecDepends(buffer, scoped["dependencies"], v+"."+platform, indent)
+		}
+	}
+
+	childVar := v + "s"
+	for _, kv := range podspecChildKeys {
+		children, _ := attributes[kv[0]].([]interface{})
+		for _, item := range children {
+			child, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := child["name"].(string)
+			buffer.WriteString("\n" + indent + v + "." + kv[1] + " " + rubyString(name) + " do |" + childVar + "|\n")
+			writePodspecAttributes(buffer, child, childVar, indent+podfileIndent, false)
+			buffer.WriteString(indent + "end\n")
+		}
+	}
+}
+
+// writePodspecPlatforms uses `platform =` for a single platform and the
+// deployment targets otherwise.
+func writePodspecPlatforms(buffer *bytes.Buffer, value interface{}, v, indent string) {
+	platforms, ok := value.(map[string]interface{})
+	if !ok {
+		buffer.WriteString(indent + v + ".attributes_hash['platforms'] = " + podspecRubyValue(value) + "\n")
+		return
+	}
+	names := make([]string, 0, len(platforms))
+	for _, platform := range SpecPlatforms {
+		if _, ok := platforms[platform]; ok {
+			names = append(names, platform)
+		}
+	}
+	others := make([]string, 0, len(platforms)-len(names))
+	for platform := range platforms {
+		if !fdt.SliceContainsStr(platform, SpecPlatforms) {
+			others = append(others, platform)
+		}
+	}
+	sort.Strings(others)
+
+	if len(names) == 1 && len(others) == 0 {
+		buffer.WriteString(indent + v + ".platform = :" + names[0])
+		if version := platforms[names[0]]; version != nil {
+			buffer.WriteString(", " + podspecRubyValue(version))
+		}
+		buffer.WriteString("\n")
+		return
+	}
+	for _, platform := range names {
+		buffer.WriteString(indent + v + "." + platform + ".deployment_target = " + podspecRubyValue(platforms[platform]) + "\n")
+	}
+	for _, platform := range others {
+		buffer.WriteString(indent + "(" + v + ".attributes_hash['platforms'] ||= {})[" + rubyString(platform) + "] = " + podspecRubyValue(platforms[platform]) + "\n")
+	}
+}
+
+func writePodspecDepends(buffer *bytes.Buffer, value interface{}, v, indent string) {
+	depends, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	names := make([]string, 0, len(depends))
+	for name := range depends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		line := indent + v + ".dependency " + rubyString(name)
+		requirements, _ := depends[name].([]interface{})
+		for _, r := range requirements {
+			line += ", " + podspecRubyValue(r)
+		}
+		buffer.WriteString(line + "\n")
+	}
+}
+
+func podspecAttributeKeys(attributes map[string]interface{}) []string {
+	keys := make([]string, 0, len(attributes))
+	for _, key := range podspecAttributeOrder {
+		if _, ok := attributes[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	others := make([]string, 0, len(attributes)-len(keys))
+	for key := range attributes {
+		if !fdt.SliceContainsStr(key, podspecAttributeOrder) {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	return append(keys, others...)
+}
+
+func podspecChildCall(key string) string {
+	for _, kv := range podspecChildKeys {
+		if kv[0] == key {
+			return kv[1]
+		}
+	}
+	return ""
+}
+
+// podspecRubyAssignValue writes the value of attribute key, a multi-line
+// string as a heredoc when that keeps its content unchanged.
+func podspecRubyAssignValue(key string, value interface{}, indent string) string {
+	str, ok := value.(string)
+	if !ok || !strings.Contains(strings.TrimSuffix(str, "\n"), "\n") || !strings.HasSuffix(str, "\n") {
+		return podspecRubyValueWithKeys(value, fdt.SliceContainsStr(key, podspecSymbolKeyAttributes))
+	}
+	id := "EOS"
+	if key == "description" {
+		id = "DESC"
+	}
+	for _, line := range strings.Split(str, "\n") {
+		if strings.TrimSpace(line) == id {
+			return podspecRubyValue(value)
+		}
+	}
+	opener := "<<-" + id
+	if strings.ContainsAny(str, "\\#") {
+		opener = "<<-'" + id + "'"
+	}
+	return opener + "\n" + str + indent + id
+}
+
+func podspecRubyValue(v interface{}) string {
+	return podspecRubyValueWithKeys(v, false)
+}
+
+func podspecRubyValueWithKeys(v interface{}, symbols bool) string {
+	switch x := v.(type) {
+	case string:
+		return rubyString(x)
+	case json.Number:
+		return x.String()
+	case bool:
+		return strconv.FormatBool(x)
+	case nil:
+		return "nil"
+	case []interface{}:
+		items := make([]string, 0, len(x))
+		for _, item := range x {
+			items = append(items, podspecRubyValueWithKeys(item, symbols))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]interface{}:
+		if len(x) == 0 {
+			return "{}"
+		}
+		keys := make([]string, 0, len(x))
+		for key := range x {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(x))
+		for _, key := range keys {
+			rubyKey := rubyString(key)
+			if symbols && regRubySymbol.MatchString(key) {
+				rubyKey = ":" + key
+			}
+			items = append(items, rubyKey+" => "+podspecRubyValueWithKeys(x[key], symbols))
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	}
+	return rubyValue(v)
+}
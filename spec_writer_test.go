@@ -0,0 +1,123 @@
+package pod
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPodspecBytesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{name: "evaluated podspec", json: ""},
+		{
+			name: "platforms",
+			json: `{"name": "Foo", "version": "1.0", "platforms": {"ios": "9.0", "osx": "10.12"},
+				"ios": {"frameworks": "UIKit"}}`,
+		},
+		{
+			name: "multi-line strings",
+			json: `{"name": "Foo", "version": "1.0", "description": "Line #1\nwith \\ backslash\n",
+				"prepare_command": "echo 'EOS'\nEOS\n"}`,
+		},
+		{
+			name: "test spec type and app spec",
+			json: `{"name": "Foo", "version": "1.0",
+				"testspecs": [{"name": "UITests", "test_type": "ui", "requires_app_host": true}],
+				"appspecs": [{"name": "App", "source_files": "App/*.swift"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := []byte(tt.json)
+			if tt.json == "" {
+				var err error
+				if want, err = PodspecJSON("Foo.podspec", []byte(testPodspec)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			aSpec, err := NewSpecWithJSONBytes(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := aSpec.PodspecBytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := PodspecJSON("Foo.podspec", b)
+			if err != nil {
+				t.Fatalf("%v\n%s", err, b)
+			}
+			if normalizedJSON(t, got) != normalizedJSON(t, want) {
+				t.Errorf("round trip of\n%s\n=\n%s\nwant\n%s", b, normalizedJSON(t, got), normalizedJSON(t, want))
+			}
+		})
+	}
+}
+
+// The unknown keys of testAFNetworkingSpec go through attributes_hash, the
+// rest must come back from the native evaluator.
+func TestPodspecBytesTrunkSpec(t *testing.T) {
+	aSpec, err := NewSpecWithJSONString(testAFNetworkingSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aSpec.Extra, aSpec.Source.Extra, aSpec.Platforms.Extra = nil, nil, nil
+	want, err := aSpec.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := aSpec.PodspecBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := PodspecJSON("AFNetworking.podspec", b)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, b)
+	}
+	if normalizedJSON(t, got) != normalizedJSON(t, want) {
+		t.Errorf("round trip of\n%s\n=\n%s\nwant\n%s", b, normalizedJSON(t, got), normalizedJSON(t, want))
+	}
+}
+
+// Attributes without DSL are written through attributes_hash, which only
+// `pod ipc spec` evaluates.
+func TestPodspecBytesAttributesHash(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{
+			name: "unknown platform",
+			json: `{"name": "Foo", "version": "1.0", "platforms": {"ios": "9.0", "visionos": "1.0"}}`,
+			want: "  s.ios.deployment_target = '9.0'\n  (s.attributes_hash['platforms'] ||= {})['visionos'] = '1.0'\n",
+		},
+		{
+			name: "unknown attribute",
+			json: `{"name": "Foo", "version": "1.0", "custom_key": {"a": [1, true]}}`,
+			want: "  s.attributes_hash['custom_key'] = { 'a' => [1, true] }\n",
+		},
+		{
+			name: "unknown platform attribute",
+			json: `{"name": "Foo", "version": "1.0", "ios": {"custom_key": "x"}}`,
+			want: "  (s.attributes_hash['ios'] ||= {})['custom_key'] = 'x'\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aSpec, err := NewSpecWithJSONString(tt.json)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := aSpec.PodspecBytes()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), tt.want) {
+				t.Errorf("PodspecBytes() =\n%s\nwant it to contain\n%s", b, tt.want)
+			}
+		})
+	}
+}